@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"math/rand"
 	"net/http"
@@ -13,15 +14,32 @@ import (
 	"time"
 
 	"github.com/folivorra/task_queue/internal/adapter/rest"
+	"github.com/folivorra/task_queue/internal/adapter/webhook"
 	"github.com/folivorra/task_queue/internal/adapter/workerpool"
 	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/filestore"
 	"github.com/folivorra/task_queue/internal/repository/inmemory"
 	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/clock"
+	"github.com/folivorra/task_queue/pkg/metrics"
 )
 
 var (
-	queueSize  int
-	workersNum int
+	queueSize       int
+	workersNum      int
+	workersMax      int
+	storage         string
+	dataDir         string
+	compactInterval time.Duration
+	cronTick        time.Duration
+	agingInterval   time.Duration
+	admission       workerpool.Admission
+	idempotencyTTL  time.Duration
+	eventHistory    int
+	webhooks        webhook.Config
+	retryPolicy     model.RetryPolicy
+	drainTimeout    time.Duration
+	autoscale       workerpool.AutoscaleConfig
 )
 
 func main() {
@@ -47,33 +65,204 @@ func main() {
 	logger.Debug("getting environment variables",
 		slog.Int("queueSize", queueSize),
 		slog.Int("workersNum", workersNum),
+		slog.Int("workersMax", workersMax),
+		slog.String("storage", storage),
+		slog.String("dataDir", dataDir),
+		slog.Duration("compactInterval", compactInterval),
+		slog.Duration("cronTick", cronTick),
+		slog.Duration("agingInterval", agingInterval),
+		slog.String("admissionPolicy", string(admission.Policy)),
+		slog.Duration("admissionTimeout", admission.Timeout),
+		slog.Int("overflowLimit", admission.OverflowLimit),
+		slog.Duration("retryAfter", admission.RetryAfter),
+		slog.Duration("idempotencyTTL", idempotencyTTL),
+		slog.Int("eventHistory", eventHistory),
+		slog.Bool("webhookSigned", webhooks.Secret != ""),
+		slog.Duration("webhookTimeout", webhooks.Timeout),
+		slog.Int("webhookMaxAttempts", webhooks.MaxAttempts),
+		slog.Duration("webhookBackoff", webhooks.BaseDelay),
+		slog.Int("webhookWorkers", webhooks.Workers),
+		slog.String("retryStrategy", string(retryPolicy.Strategy)),
+		slog.Duration("retryBase", time.Duration(retryPolicy.Base)),
+		slog.Duration("retryMax", time.Duration(retryPolicy.Max)),
+		slog.Float64("retryMultiplier", retryPolicy.Multiplier),
+		slog.Float64("retryJitter", retryPolicy.Jitter),
+		slog.Duration("drainTimeout", drainTimeout),
+		slog.Int("autoscaleMin", autoscale.Min),
+		slog.Int("autoscaleMax", autoscale.Max),
 	)
 
-	// repo service
-	taskRepo := inmemory.NewTaskInMemoryRepo()
-	taskService := usecase.NewTaskService(taskRepo)
+	wg := &sync.WaitGroup{}
+
+	// repo
+	var (
+		taskRepo        usecase.TaskRepo
+		jobRepo         usecase.JobRepo
+		deadLetterRepo  usecase.DeadLetterRepo
+		idempotencyRepo usecase.IdempotencyRepo
+		pauseRepo       usecase.PauseRepo
+	)
+	switch storage {
+	case "file":
+		taskFileRepo, err := filestore.NewTaskFileRepo(dataDir)
+		if err != nil {
+			fatal(logger, "failed to open task storage", err)
+		}
+		jobFileRepo, err := filestore.NewJobFileRepo(dataDir)
+		if err != nil {
+			fatal(logger, "failed to open job storage", err)
+		}
+		deadLetterFileRepo, err := filestore.NewDeadLetterFileRepo(dataDir)
+		if err != nil {
+			fatal(logger, "failed to open dead letter storage", err)
+		}
+		idempotencyFileRepo, err := filestore.NewIdempotencyFileRepo(dataDir)
+		if err != nil {
+			fatal(logger, "failed to open idempotency storage", err)
+		}
+		pauseFileRepo, err := filestore.NewPauseFileRepo(dataDir)
+		if err != nil {
+			fatal(logger, "failed to open pause storage", err)
+		}
+		defer closeAll(logger, taskFileRepo, jobFileRepo, deadLetterFileRepo, idempotencyFileRepo, pauseFileRepo)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filestore.RunCompaction(ctx, compactInterval, func(err error) {
+				logger.Error("wal compaction failed",
+					slog.String("err", err.Error()),
+				)
+			}, taskFileRepo, jobFileRepo, deadLetterFileRepo, idempotencyFileRepo, pauseFileRepo)
+		}()
+
+		taskRepo = taskFileRepo
+		jobRepo = jobFileRepo
+		deadLetterRepo = deadLetterFileRepo
+		idempotencyRepo = idempotencyFileRepo
+		pauseRepo = pauseFileRepo
+	default:
+		taskRepo = inmemory.NewTaskInMemoryRepo()
+		jobRepo = inmemory.NewJobInMemoryRepo()
+		deadLetterRepo = inmemory.NewDeadLetterInMemoryRepo()
+		idempotencyRepo = inmemory.NewIdempotencyInMemoryRepo()
+		pauseRepo = inmemory.NewPauseInMemoryRepo()
+	}
+
+	// metrics
+	registry := metrics.NewRegistry()
+
+	// events
+	eventBus := usecase.NewEventBus(eventHistory)
+
+	// service
+	taskService := usecase.NewTaskService(taskRepo,
+		usecase.WithMetrics(usecase.NewMetrics(registry)),
+		usecase.WithEvents(eventBus),
+		usecase.WithRetryPolicy(retryPolicy),
+	)
+	taskService.Register("simulation", usecase.Simulation)
+	jobService := usecase.NewJobService(jobRepo, taskService)
+	deadLetterService := usecase.NewDeadLetterService(deadLetterRepo, taskService)
+	idempotencyService := usecase.NewIdempotencyService(idempotencyRepo, idempotencyTTL, clock.Real{})
+	pauseService := usecase.NewPauseService(pauseRepo)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		idempotencyService.RunExpiry(ctx, min(idempotencyTTL, time.Minute), func(err error) {
+			logger.Error("idempotency keys expiry failed",
+				slog.String("err", err.Error()),
+			)
+		})
+	}()
 
 	// worker pool
-	wg := &sync.WaitGroup{}
-	workerPool := workerpool.NewWorkerPool(taskService, workersNum,
-		make(chan *model.Task, queueSize), make(chan *model.Task, queueSize), wg, logger)
+	workerPool := workerpool.NewWorkerPool(taskService, deadLetterService, workersNum,
+		workerpool.NewPriorityQueue(queueSize, agingInterval), make(chan *model.Task, queueSize), admission, wg, logger)
+	workerPool.SetMaxWorkers(workersMax)
+	// пауза, сохранённая до рестарта, действует с первой задачи
+	workerPool.SetPaused(pauseService.State())
+	pauseService.OnChange(workerPool.SetPaused)
 	workerPool.Run(ctx)
+	registry.NewGaugeFunc("taskqueue_queue_depth", "Tasks waiting for a worker.", func() float64 {
+		return float64(workerPool.QueueLen())
+	})
+	registry.NewGaugeFunc("taskqueue_retry_queue_depth", "Tasks waiting for a retry.", func() float64 {
+		return float64(workerPool.RetryLen())
+	})
+	registry.NewGaugeFunc("taskqueue_workers", "Running workers, including ones finishing their last task after a shrink.", func() float64 {
+		return float64(workerPool.Workers().Running)
+	})
+
+	// webhooks
+	dispatcher := webhook.NewDispatcher(taskService, webhooks, wg, logger)
+	taskService.OnFinished(dispatcher.Notify)
+	dispatcher.Run(ctx)
+
+	// recovery
+	pending, err := taskService.Recover()
+	if err != nil {
+		fatal(logger, "failed to recover tasks", err)
+	}
+	workerPool.Resubmit(pending)
+	logger.Info("recovered pending tasks",
+		slog.Int("count", len(pending)),
+	)
+	logger.Info("recovered pending webhooks",
+		slog.Int("count", dispatcher.Resume()),
+	)
+
+	// cron
+	cronScheduler := workerpool.NewCronScheduler(jobService, workerPool, cronTick, wg, logger)
+	cronScheduler.Run(ctx)
+
+	// autoscaler
+	if autoscale.Max > 0 {
+		autoscaler := workerpool.NewAutoscaler(workerPool, autoscale, registry, wg, logger)
+		autoscaler.Run(ctx)
+	}
 
 	// controller
-	taskController := rest.NewTaskController(taskService, workerPool)
+	taskController := rest.NewTaskController(taskService, workerPool, idempotencyService)
+	jobController := rest.NewJobController(jobService)
+	deadLetterController := rest.NewDeadLetterController(deadLetterService, workerPool)
+	metricsController := rest.NewMetricsController(registry)
+	eventController := rest.NewEventController(eventBus)
+	retryController := rest.NewRetryController(workerPool)
+	adminController := rest.NewAdminController(workerPool, pauseService)
 
 	// mux
 	mux := http.NewServeMux()
 	mux.HandleFunc("/enqueue", taskController.Enqueue)
+	mux.HandleFunc("/enqueue/batch", taskController.EnqueueBatch)
 	mux.HandleFunc("/healthz", taskController.Healthcheck)
 	mux.HandleFunc("/task", taskController.GetTask)
+	mux.HandleFunc("/task/cancel", taskController.CancelTask)
+	mux.HandleFunc("/task/wait", taskController.WaitTask)
 	mux.HandleFunc("/tasks", taskController.GetTaskList)
+	mux.HandleFunc("/jobs", jobController.Jobs)
+	mux.HandleFunc("/job", jobController.Job)
+	mux.HandleFunc("/job/pause", jobController.Pause)
+	mux.HandleFunc("/job/resume", jobController.Resume)
+	mux.HandleFunc("/deadletters", deadLetterController.DeadLetters)
+	mux.HandleFunc("/deadletters/requeue", deadLetterController.Requeue)
+	mux.HandleFunc("/metrics", metricsController.Metrics)
+	mux.HandleFunc("/events", eventController.Events)
+	mux.HandleFunc("/retries", retryController.Retries)
+	mux.HandleFunc("/retries/reschedule", retryController.Reschedule)
+	mux.HandleFunc("/admin/workers", adminController.Workers)
+	mux.HandleFunc("/admin/pause", adminController.Pause)
+	mux.HandleFunc("/admin/resume", adminController.Resume)
 
 	// server
-	server := rest.NewServer(&http.Server{
+	httpServer := &http.Server{
 		Addr:    ":8080",
 		Handler: mux,
-	}, logger)
+	}
+	// SSE-потоки сами не завершаются, поэтому при остановке их закрывает шина
+	httpServer.RegisterOnShutdown(eventBus.Close)
+	server := rest.NewServer(httpServer, logger)
 
 	go func() {
 		if err := server.Run(); err != nil {
@@ -89,6 +278,9 @@ func main() {
 	<-shutdownCh
 	logger.Info("received shutdown signal")
 
+	// пока пул дренируется, API продолжает отвечать, а enqueue получает 503
+	workerPool.Drain(drainTimeout)
+
 	if err := server.Stop(); err != nil {
 		logger.Error("server stopped incorrectly",
 			slog.String("err", err.Error()),
@@ -97,7 +289,6 @@ func main() {
 
 	cancel()
 	wg.Wait()
-	workerPool.Shutdown()
 }
 
 func getENV() {
@@ -121,4 +312,157 @@ func getENV() {
 		workersNum = 4
 	}
 
+	workersMax, err = strconv.Atoi(os.Getenv("WORKERS_MAX"))
+	if err != nil || workersMax <= 0 {
+		workersMax = workerpool.DefaultMaxWorkers
+	}
+	workersNum = min(workersNum, workersMax)
+
+	storage = os.Getenv("STORAGE")
+	if storage == "" {
+		storage = "memory"
+	}
+
+	dataDir = os.Getenv("DATA_DIR")
+	if dataDir == "" {
+		dataDir = "data"
+	}
+
+	compactInterval, err = time.ParseDuration(os.Getenv("COMPACT_INTERVAL"))
+	if err != nil || compactInterval <= 0 {
+		compactInterval = time.Minute
+	}
+
+	cronTick, err = time.ParseDuration(os.Getenv("CRON_TICK"))
+	if err != nil || cronTick <= 0 {
+		cronTick = time.Second
+	}
+
+	agingInterval, err = time.ParseDuration(os.Getenv("PRIORITY_AGING"))
+	if err != nil || agingInterval < 0 {
+		agingInterval = 30 * time.Second
+	}
+
+	admission.Policy, err = workerpool.ParseAdmissionPolicy(os.Getenv("ADMISSION_POLICY"))
+	if err != nil {
+		admission.Policy = workerpool.AdmitReject
+	}
+
+	admission.Timeout, err = time.ParseDuration(os.Getenv("ADMISSION_TIMEOUT"))
+	if err != nil || admission.Timeout < 0 {
+		admission.Timeout = time.Second
+	}
+
+	overflowLimitStr := os.Getenv("OVERFLOW_LIMIT")
+	if overflowLimitStr == "" {
+		overflowLimitStr = "10000"
+	}
+	admission.OverflowLimit, err = strconv.Atoi(overflowLimitStr)
+	if err != nil {
+		admission.OverflowLimit = 10000
+	}
+
+	admission.RetryAfter, err = time.ParseDuration(os.Getenv("RETRY_AFTER"))
+	if err != nil || admission.RetryAfter <= 0 {
+		admission.RetryAfter = time.Second
+	}
+
+	idempotencyTTL, err = time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || idempotencyTTL <= 0 {
+		idempotencyTTL = 24 * time.Hour
+	}
+
+	eventHistoryStr := os.Getenv("EVENT_HISTORY")
+	if eventHistoryStr == "" {
+		eventHistoryStr = "1024"
+	}
+	eventHistory, err = strconv.Atoi(eventHistoryStr)
+	if err != nil || eventHistory <= 0 {
+		eventHistory = usecase.DefaultEventHistory
+	}
+
+	webhooks.Secret = os.Getenv("WEBHOOK_SECRET")
+
+	webhooks.Timeout, err = time.ParseDuration(os.Getenv("WEBHOOK_TIMEOUT"))
+	if err != nil || webhooks.Timeout <= 0 {
+		webhooks.Timeout = 10 * time.Second
+	}
+
+	webhookAttemptsStr := os.Getenv("WEBHOOK_MAX_ATTEMPTS")
+	if webhookAttemptsStr == "" {
+		webhookAttemptsStr = "5"
+	}
+	webhooks.MaxAttempts, err = strconv.Atoi(webhookAttemptsStr)
+	if err != nil || webhooks.MaxAttempts <= 0 {
+		webhooks.MaxAttempts = 5
+	}
+
+	webhooks.BaseDelay, err = time.ParseDuration(os.Getenv("WEBHOOK_BACKOFF"))
+	if err != nil || webhooks.BaseDelay <= 0 {
+		webhooks.BaseDelay = time.Second
+	}
+	webhooks.MaxDelay = time.Minute
+
+	webhooks.Workers, err = strconv.Atoi(os.Getenv("WEBHOOK_WORKERS"))
+	if err != nil || webhooks.Workers <= 0 {
+		webhooks.Workers = webhook.DefaultWorkers
+	}
+
+	retryPolicy = model.DefaultRetryPolicy()
+	if strategy := os.Getenv("RETRY_STRATEGY"); strategy != "" {
+		retryPolicy.Strategy = model.RetryStrategy(strategy)
+		retryPolicy.Multiplier = 0
+	}
+	if base, err := time.ParseDuration(os.Getenv("RETRY_BASE")); err == nil {
+		retryPolicy.Base = model.Duration(base)
+	}
+	if maxDelay, err := time.ParseDuration(os.Getenv("RETRY_MAX")); err == nil {
+		retryPolicy.Max = model.Duration(maxDelay)
+	}
+	if multiplier, err := strconv.ParseFloat(os.Getenv("RETRY_MULTIPLIER"), 64); err == nil {
+		retryPolicy.Multiplier = multiplier
+	}
+	if jitter, err := strconv.ParseFloat(os.Getenv("RETRY_JITTER"), 64); err == nil {
+		retryPolicy.Jitter = jitter
+	}
+	if retryPolicy.Validate() != nil {
+		retryPolicy = model.DefaultRetryPolicy()
+	}
+
+	drainTimeout, err = time.ParseDuration(os.Getenv("DRAIN_TIMEOUT"))
+	if err != nil || drainTimeout < 0 {
+		drainTimeout = 30 * time.Second
+	}
+
+	// автоскейлер включается заданием AUTOSCALE_MAX, остальное — по умолчанию
+	autoscale.Max, _ = strconv.Atoi(os.Getenv("AUTOSCALE_MAX"))
+	autoscale.Max = min(autoscale.Max, workersMax)
+	autoscale.Min, err = strconv.Atoi(os.Getenv("AUTOSCALE_MIN"))
+	if err != nil || autoscale.Min <= 0 {
+		autoscale.Min = 1
+	}
+	// иначе Max поднимется до Min, и каждый Resize автоскейлера упрётся в WORKERS_MAX
+	autoscale.Min = min(autoscale.Min, workersMax)
+	autoscale.Interval, _ = time.ParseDuration(os.Getenv("AUTOSCALE_INTERVAL"))
+	autoscale.UpCooldown, _ = time.ParseDuration(os.Getenv("AUTOSCALE_UP_COOLDOWN"))
+	autoscale.DownCooldown, _ = time.ParseDuration(os.Getenv("AUTOSCALE_DOWN_COOLDOWN"))
+	autoscale.QueuePerWorker, _ = strconv.ParseFloat(os.Getenv("AUTOSCALE_QUEUE_PER_WORKER"), 64)
+	autoscale.MaxQueueWait, _ = time.ParseDuration(os.Getenv("AUTOSCALE_MAX_QUEUE_WAIT"))
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg,
+		slog.String("err", err.Error()),
+	)
+	os.Exit(1)
+}
+
+func closeAll(logger *slog.Logger, closers ...io.Closer) {
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			logger.Error("failed to close storage",
+				slog.String("err", err.Error()),
+			)
+		}
+	}
 }
@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/folivorra/task_queue/internal/adapter/workerpool"
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/usecase"
+)
+
+type AdminController struct {
+	processor *workerpool.WorkerPool
+	pauses    *usecase.PauseService
+}
+
+func NewAdminController(processor *workerpool.WorkerPool, pauses *usecase.PauseService) *AdminController {
+	return &AdminController{
+		processor: processor,
+		pauses:    pauses,
+	}
+}
+
+type resizeRequest struct {
+	Workers int `json:"workers"`
+}
+
+// Workers: GET — размер пула, PUT {"workers": n} — изменить его на ходу.
+func (ac *AdminController) Workers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, ac.processor.Workers())
+	case http.MethodPut:
+		if r.Body == nil {
+			writeJSONError(w, http.StatusBadRequest, "empty body")
+			return
+		}
+		defer r.Body.Close()
+
+		var req resizeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+
+		if err := ac.processor.Resize(req.Workers); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ac.processor.Workers())
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// Pause приостанавливает выдачу задач типа ?type=, без type — всех задач.
+func (ac *AdminController) Pause(w http.ResponseWriter, r *http.Request) {
+	ac.changePause(w, r, ac.pauses.Pause)
+}
+
+// Resume снимает паузу с типа ?type=, без type — все паузы.
+func (ac *AdminController) Resume(w http.ResponseWriter, r *http.Request) {
+	ac.changePause(w, r, ac.pauses.Resume)
+}
+
+func (ac *AdminController) changePause(w http.ResponseWriter, r *http.Request, change func(taskType string) (model.PauseState, error)) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	state, err := change(r.URL.Query().Get("type"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
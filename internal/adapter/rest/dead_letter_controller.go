@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/folivorra/task_queue/internal/adapter/workerpool"
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type DeadLetterController struct {
+	service   *usecase.DeadLetterService
+	processor *workerpool.WorkerPool
+}
+
+func NewDeadLetterController(service *usecase.DeadLetterService, processor *workerpool.WorkerPool) *DeadLetterController {
+	return &DeadLetterController{
+		service:   service,
+		processor: processor,
+	}
+}
+
+func (dc *DeadLetterController) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		if id == "" {
+			writeJSON(w, http.StatusOK, dc.service.List())
+			return
+		}
+
+		dl, err := dc.service.Get(id)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, dl)
+	case http.MethodDelete:
+		if id != "" {
+			if err := dc.service.Purge(id); err != nil {
+				writeServiceError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]int{"purged": 1})
+			return
+		}
+
+		purged, err := dc.service.PurgeAll()
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"purged": purged})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (dc *DeadLetterController) Requeue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	id := query.Get("id")
+
+	// задачи проходят тот же контроль приёма, что и новые
+	admit := func(task *model.Task) error {
+		return dc.processor.Admit(r.Context(), task)
+	}
+
+	switch {
+	case id != "":
+		task, err := dc.service.Requeue(id, admit)
+		if err != nil {
+			writeEnqueueError(w, dc.processor, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, []*model.Task{task})
+	case query.Get("all") == "true":
+		result, err := dc.service.RequeueAll(admit)
+		if err != nil && len(result.Requeued) == 0 {
+			writeJSON(w, dc.requeueFailureStatus(w, err), result)
+			return
+		}
+		// частичный успех: непринятые задачи перечислены в failed
+		writeJSON(w, http.StatusOK, result)
+	default:
+		writeJSONError(w, http.StatusBadRequest, "missing id or all=true parameter")
+	}
+}
+
+// requeueFailureStatus — статус ответа, когда ни один dead letter не вернулся
+// в очередь: как у отказа в приёме одной задачи.
+func (dc *DeadLetterController) requeueFailureStatus(w http.ResponseWriter, err error) int {
+	switch {
+	case errors.Is(err, apperrors.ErrOverloaded):
+		setRetryAfter(w, dc.processor.RetryAfter())
+		return http.StatusTooManyRequests
+	case errors.Is(err, apperrors.ErrUnavailable):
+		setRetryAfter(w, dc.processor.RetryAfter())
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
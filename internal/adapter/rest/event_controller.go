@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/usecase"
+)
+
+const sseHeartbeat = 15 * time.Second
+
+type EventController struct {
+	bus *usecase.EventBus
+}
+
+func NewEventController(bus *usecase.EventBus) *EventController {
+	return &EventController{
+		bus: bus,
+	}
+}
+
+// Events отдаёт поток событий о задачах в формате Server-Sent Events.
+// Фильтры: id, type, status. Заголовок Last-Event-ID (или параметр
+// last_event_id) продолжает поток после указанного события; если часть
+// событий уже вытеснена из буфера, первым приходит событие reset.
+func (ec *EventController) Events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	values := r.URL.Query()
+	filter := model.EventFilter{
+		TaskIDs: splitList(values["id"]),
+		Types:   splitList(values["type"]),
+	}
+	for _, status := range splitList(values["status"]) {
+		filter.Statuses = append(filter.Statuses, model.TaskStatus(status))
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = values.Get("last_event_id")
+	}
+
+	var (
+		sub      *usecase.Subscription
+		replay   []model.TaskEvent
+		complete = true
+	)
+	if lastID != "" {
+		lastSeq, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		sub, replay, complete = ec.bus.SubscribeFrom(filter, lastSeq)
+	} else {
+		sub = ec.bus.Subscribe(filter)
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, ev := range replay {
+		writeEvent(w, ev)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			writeEvent(w, ev)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, ev model.TaskEvent) {
+	data, _ := json.Marshal(ev)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Status, data)
+}
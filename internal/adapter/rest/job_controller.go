@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type JobController struct {
+	service *usecase.JobService
+}
+
+func NewJobController(service *usecase.JobService) *JobController {
+	return &JobController{
+		service: service,
+	}
+}
+
+func (jc *JobController) Jobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, jc.service.List())
+	case http.MethodPost:
+		jc.create(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (jc *JobController) create(w http.ResponseWriter, r *http.Request) {
+	if r.Body == nil {
+		writeJSONError(w, http.StatusBadRequest, "empty body")
+		return
+	}
+	defer r.Body.Close()
+
+	var req model.CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	job := &model.RecurringJob{
+		ID:         req.ID,
+		Schedule:   req.Schedule,
+		Type:       req.Type,
+		Payload:    req.Payload,
+		MaxRetries: req.MaxRetries,
+		CatchUp:    req.CatchUp,
+	}
+
+	if err := jc.service.Create(job); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, job)
+}
+
+func (jc *JobController) Job(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing id parameter")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		job, err := jc.service.Get(id)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	case http.MethodDelete:
+		if err := jc.service.Delete(id); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (jc *JobController) Pause(w http.ResponseWriter, r *http.Request) {
+	jc.toggle(w, r, jc.service.Pause)
+}
+
+func (jc *JobController) Resume(w http.ResponseWriter, r *http.Request) {
+	jc.toggle(w, r, jc.service.Resume)
+}
+
+func (jc *JobController) toggle(w http.ResponseWriter, r *http.Request, action func(id string) (*model.RecurringJob, error)) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing id parameter")
+		return
+	}
+
+	job, err := action(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, apperrors.ErrAlreadyExists),
+		errors.Is(err, apperrors.ErrConflict):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, apperrors.ErrInvalidData):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, apperrors.ErrUnprocessable):
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, apperrors.ErrUnavailable):
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	}
+}
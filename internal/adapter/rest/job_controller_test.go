@@ -0,0 +1,111 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/folivorra/task_queue/internal/adapter/rest"
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/inmemory"
+	"github.com/folivorra/task_queue/internal/usecase"
+)
+
+func setupJobServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	taskService := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	taskService.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	jobController := rest.NewJobController(usecase.NewJobService(inmemory.NewJobInMemoryRepo(), taskService))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", jobController.Jobs)
+	mux.HandleFunc("/job", jobController.Job)
+	mux.HandleFunc("/job/pause", jobController.Pause)
+	mux.HandleFunc("/job/resume", jobController.Resume)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestJobEndpoints(t *testing.T) {
+	server := setupJobServer(t)
+
+	body, _ := json.Marshal(model.CreateJobRequest{
+		ID:       "nightly",
+		Schedule: "0 3 * * *",
+		Type:     "noop",
+		CatchUp:  model.CatchUpOnce,
+	})
+	resp, err := http.Post(server.URL+"/jobs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(server.URL+"/job/pause?id=nightly", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var paused model.RecurringJob
+	_ = json.NewDecoder(resp.Body).Decode(&paused)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !paused.Paused {
+		t.Fatalf("expected paused job, got %d %+v", resp.StatusCode, paused)
+	}
+
+	resp, err = http.Get(server.URL + "/jobs")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var jobs []*model.RecurringJob
+	_ = json.NewDecoder(resp.Body).Decode(&jobs)
+	resp.Body.Close()
+	if len(jobs) != 1 || jobs[0].ID != "nightly" || jobs[0].NextRunAt == nil {
+		t.Fatalf("unexpected job list: %+v", jobs)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/job?id=nightly", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/job?id=nightly")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestJobEndpoints_InvalidSchedule(t *testing.T) {
+	server := setupJobServer(t)
+
+	body := []byte(`{"id":"bad","schedule":"61 * * * *","type":"noop"}`)
+	resp, err := http.Post(server.URL+"/jobs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
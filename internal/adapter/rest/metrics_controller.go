@@ -0,0 +1,28 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/folivorra/task_queue/pkg/metrics"
+)
+
+type MetricsController struct {
+	registry *metrics.Registry
+}
+
+func NewMetricsController(registry *metrics.Registry) *MetricsController {
+	return &MetricsController{
+		registry: registry,
+	}
+}
+
+func (mc *MetricsController) Metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = mc.registry.WriteTo(w)
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/adapter/workerpool"
+)
+
+type RetryController struct {
+	processor *workerpool.WorkerPool
+}
+
+func NewRetryController(processor *workerpool.WorkerPool) *RetryController {
+	return &RetryController{
+		processor: processor,
+	}
+}
+
+// Retries: GET — ожидающие повторы в порядке запуска, DELETE ?id= — отмена
+// задачи, ожидающей повтора.
+func (rc *RetryController) Retries(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, rc.processor.PendingRetries())
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "missing id parameter")
+			return
+		}
+
+		task, err := rc.processor.CancelRetry(id)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// Reschedule переносит повтор на время at (RFC 3339) или через delay от
+// текущего момента; без параметров повтор запускается сразу.
+func (rc *RetryController) Reschedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	id := query.Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing id parameter")
+		return
+	}
+
+	at := time.Now()
+	switch {
+	case query.Get("at") != "":
+		parsed, err := time.Parse(time.RFC3339, query.Get("at"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid at parameter")
+			return
+		}
+		at = parsed
+	case query.Get("delay") != "":
+		delay, err := time.ParseDuration(query.Get("delay"))
+		if err != nil || delay < 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid delay parameter")
+			return
+		}
+		at = at.Add(delay)
+	}
+
+	retry, err := rc.processor.RescheduleRetry(id, at)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, retry)
+}
@@ -0,0 +1,265 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/adapter/workerpool"
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+const maxBatchSize = 10000
+
+var errBatchTooLarge = fmt.Errorf("batch exceeds %d items", maxBatchSize)
+
+// EnqueueBatch принимает JSON-массив CreateTaskRequest или NDJSON
+// (Content-Type: application/x-ndjson). Тело читается потоково: в режиме
+// partial задачи создаются по мере чтения.
+func (tc *TaskController) EnqueueBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if r.Body == nil {
+		writeJSONError(w, http.StatusBadRequest, "empty body")
+		return
+	}
+	defer r.Body.Close()
+
+	mode, err := model.ParseBatchMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := newBatchReader(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if mode == model.BatchAtomic {
+		tc.enqueueAtomic(r.Context(), w, items)
+		return
+	}
+	tc.enqueuePartial(r.Context(), w, items)
+}
+
+func (tc *TaskController) enqueuePartial(ctx context.Context, w http.ResponseWriter, items *batchReader) {
+	result := model.BatchResult{Mode: model.BatchPartial, Results: []model.BatchItemResult{}}
+
+	for i := 0; ; i++ {
+		req, ok, err := items.next()
+		if err != nil {
+			result.Error = err.Error()
+			writeJSON(w, batchReadErrorStatus(err), result)
+			return
+		}
+		if !ok {
+			break
+		}
+
+		item := model.BatchItemResult{Index: i, Status: model.BatchCreated}
+		task, err := tc.create(ctx, req)
+		if err != nil {
+			item.Status = batchItemStatus(err)
+			item.Error = err.Error()
+		} else {
+			item.Task = task
+			result.Created++
+		}
+		result.Results = append(result.Results, item)
+	}
+
+	if len(result.Results) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "empty batch")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (tc *TaskController) enqueueAtomic(ctx context.Context, w http.ResponseWriter, items *batchReader) {
+	var (
+		tasks   []*model.Task
+		results []model.BatchItemResult
+		failed  bool
+		seen    = make(map[string]struct{})
+	)
+
+	for i := 0; ; i++ {
+		req, ok, err := items.next()
+		if err != nil {
+			writeJSONError(w, batchReadErrorStatus(err), err.Error())
+			return
+		}
+		if !ok {
+			break
+		}
+
+		task, err := req.NewTask(time.Now())
+		if err == nil {
+			err = tc.service.Check(task)
+		}
+		if err == nil {
+			if _, dup := seen[task.ID]; dup {
+				err = fmt.Errorf("%w: duplicate id %q within batch", apperrors.ErrAlreadyExists, task.ID)
+			}
+			seen[task.ID] = struct{}{}
+		}
+
+		item := model.BatchItemResult{Index: i, Status: model.BatchSkipped}
+		if err != nil {
+			item.Status = batchItemStatus(err)
+			item.Error = err.Error()
+			failed = true
+		}
+		tasks = append(tasks, task)
+		results = append(results, item)
+	}
+
+	if len(results) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "empty batch")
+		return
+	}
+
+	result := model.BatchResult{Mode: model.BatchAtomic, Results: results}
+	if failed {
+		writeJSON(w, atomicFailureStatus(results), result)
+		return
+	}
+
+	if i, err := tc.service.SaveAll(tasks); err != nil {
+		results[i].Status = batchItemStatus(err)
+		results[i].Error = err.Error()
+		writeJSON(w, atomicFailureStatus(results), result)
+		return
+	}
+
+	if err := tc.processor.AdmitAll(ctx, tasks); err != nil {
+		for i, task := range tasks {
+			if rbErr := tc.service.Discard(task.ID); rbErr != nil {
+				err = errors.Join(err, rbErr)
+			}
+			results[i].Status = model.BatchRejected
+		}
+		for i := range results {
+			results[i].Error = err.Error()
+		}
+		tc.writeBatchRejected(w, err, result)
+		return
+	}
+
+	for i, task := range tasks {
+		results[i].Status = model.BatchCreated
+		results[i].Task = task
+	}
+	result.Created = len(tasks)
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func (tc *TaskController) writeBatchRejected(w http.ResponseWriter, err error, result model.BatchResult) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, workerpool.ErrBatchTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, apperrors.ErrOverloaded):
+		status = http.StatusTooManyRequests
+		setRetryAfter(w, tc.processor.RetryAfter())
+	case errors.Is(err, apperrors.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+		setRetryAfter(w, tc.processor.RetryAfter())
+	}
+
+	writeJSON(w, status, result)
+}
+
+func batchItemStatus(err error) model.BatchItemStatus {
+	switch {
+	case errors.Is(err, apperrors.ErrAlreadyExists):
+		return model.BatchConflict
+	case errors.Is(err, apperrors.ErrInvalidData):
+		return model.BatchInvalid
+	case errors.Is(err, apperrors.ErrOverloaded),
+		errors.Is(err, apperrors.ErrUnavailable):
+		return model.BatchRejected
+	default:
+		return model.BatchFailed
+	}
+}
+
+// atomicFailureStatus: 400, если в пачке есть некорректные задачи, иначе 409
+// для конфликтов id и 500 для прочих ошибок.
+func atomicFailureStatus(results []model.BatchItemResult) int {
+	status := http.StatusInternalServerError
+	for _, item := range results {
+		switch item.Status {
+		case model.BatchInvalid:
+			return http.StatusBadRequest
+		case model.BatchConflict:
+			status = http.StatusConflict
+		}
+	}
+	return status
+}
+
+func batchReadErrorStatus(err error) int {
+	if errors.Is(err, errBatchTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// batchReader последовательно декодирует элементы JSON-массива или NDJSON.
+type batchReader struct {
+	dec   *json.Decoder
+	array bool
+	count int
+}
+
+func newBatchReader(r *http.Request) (*batchReader, error) {
+	br := &batchReader{dec: json.NewDecoder(r.Body), array: true}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/x-ndjson" {
+		br.array = false
+		return br, nil
+	}
+
+	if tok, err := br.dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("invalid JSON: expected an array of tasks")
+	}
+
+	return br, nil
+}
+
+func (br *batchReader) next() (model.CreateTaskRequest, bool, error) {
+	var req model.CreateTaskRequest
+
+	if !br.dec.More() {
+		if br.array {
+			if _, err := br.dec.Token(); err != nil {
+				return req, false, fmt.Errorf("invalid JSON at item %d", br.count)
+			}
+		}
+		return req, false, nil
+	}
+
+	if br.count == maxBatchSize {
+		return req, false, errBatchTooLarge
+	}
+
+	if err := br.dec.Decode(&req); err != nil {
+		return req, false, fmt.Errorf("invalid JSON at item %d", br.count)
+	}
+	br.count++
+
+	return req, true, nil
+}
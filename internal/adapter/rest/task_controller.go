@@ -1,9 +1,15 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/folivorra/task_queue/internal/adapter/workerpool"
 	"github.com/folivorra/task_queue/internal/model"
@@ -11,15 +17,22 @@ import (
 	"github.com/folivorra/task_queue/pkg/apperrors"
 )
 
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 5 * time.Minute
+)
+
 type TaskController struct {
-	service   *usecase.TaskService
-	processor *workerpool.WorkerPool
+	service     *usecase.TaskService
+	processor   *workerpool.WorkerPool
+	idempotency *usecase.IdempotencyService
 }
 
-func NewTaskController(service *usecase.TaskService, processor *workerpool.WorkerPool) *TaskController {
+func NewTaskController(service *usecase.TaskService, processor *workerpool.WorkerPool, idempotency *usecase.IdempotencyService) *TaskController {
 	return &TaskController{
-		service:   service,
-		processor: processor,
+		service:     service,
+		processor:   processor,
+		idempotency: idempotency,
 	}
 }
 
@@ -41,30 +54,89 @@ func (tc *TaskController) Enqueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task := &model.Task{
-		ID:         req.ID,
-		Payload:    req.Payload,
-		MaxRetries: req.MaxRetries,
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		task, err := tc.create(r.Context(), req)
+		if err != nil {
+			writeEnqueueError(w, tc.processor, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, task)
+		return
 	}
 
-	if err := tc.service.Save(task); err != nil {
-		switch {
-		case errors.Is(err, apperrors.ErrAlreadyExists):
-			writeJSONError(w, http.StatusConflict, err.Error())
-		case errors.Is(err, apperrors.ErrInvalidData):
-			writeJSONError(w, http.StatusBadRequest, err.Error())
-		default:
-			writeJSONError(w, http.StatusInternalServerError, err.Error())
-		}
+	rec, err := tc.idempotency.Begin(key, req.Fingerprint())
+	if err != nil {
+		writeEnqueueError(w, tc.processor, err)
+		return
+	}
+	if rec != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(rec.StatusCode)
+		_, _ = w.Write(rec.Body)
+		return
+	}
+
+	task, err := tc.create(r.Context(), req)
+	if err != nil {
+		tc.idempotency.Release(key)
+		writeEnqueueError(w, tc.processor, err)
+		return
+	}
+
+	body, err := json.Marshal(task)
+	if err != nil {
+		tc.idempotency.Release(key)
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	tc.processor.PushToQueue(task)
+	// задача уже принята: если ответ не удалось сохранить, клиент всё равно
+	// получает 201, а повтор с этим ключом будет обработан как новый запрос
+	if err := tc.idempotency.Complete(key, http.StatusCreated, body); err != nil {
+		tc.idempotency.Release(key)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(task); err != nil {
-		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	_, _ = w.Write(body)
+}
+
+func (tc *TaskController) create(ctx context.Context, req model.CreateTaskRequest) (*model.Task, error) {
+	task, err := req.NewTask(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tc.service.Save(task); err != nil {
+		return nil, err
+	}
+
+	if err := tc.processor.Admit(ctx, task); err != nil {
+		// задача не попала в очередь: откатываем сохранение, чтобы клиент
+		// мог повторить запрос с тем же id
+		if rbErr := tc.service.Discard(task.ID); rbErr != nil {
+			return nil, errors.Join(err, rbErr)
+		}
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// writeEnqueueError отвечает на отказ в приёме задачи: 429 при переполнении
+// очереди, 503 при остановке пула.
+func writeEnqueueError(w http.ResponseWriter, processor *workerpool.WorkerPool, err error) {
+	switch {
+	case errors.Is(err, apperrors.ErrOverloaded):
+		setRetryAfter(w, processor.RetryAfter())
+		writeJSONError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, apperrors.ErrUnavailable):
+		setRetryAfter(w, processor.RetryAfter())
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeServiceError(w, err)
 	}
 }
 
@@ -74,11 +146,26 @@ func (tc *TaskController) Healthcheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "ok"}); err != nil {
-		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	writeJSON(w, http.StatusOK, healthResponse{
+		Status: "ok",
+		Paused: tc.processor.Paused(),
+	})
+}
+
+// healthResponse: пауза не делает сервис нездоровым — задачи принимаются,
+// поэтому статус остаётся ok, а пауза сообщается отдельно.
+type healthResponse struct {
+	Status string           `json:"status"`
+	Paused model.PauseState `json:"paused"`
+}
+
+// setRetryAfter выставляет Retry-After в целых секундах, не меньше одной.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
 	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
 }
 
 func writeJSONError(w http.ResponseWriter, status int, msg string) {
@@ -119,12 +206,158 @@ func (tc *TaskController) GetTask(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// WaitTask держит запрос, пока задача не завершится окончательно или не
+// истечёт timeout (по умолчанию defaultWaitTimeout). Завершённая задача
+// возвращается с 200, незавершённая по таймауту — с 202.
+func (tc *TaskController) WaitTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing id parameter")
+		return
+	}
+
+	timeout := defaultWaitTimeout
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 || d > maxWaitTimeout {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid timeout parameter: expected duration up to %s", maxWaitTimeout))
+			return
+		}
+		timeout = d
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	task, err := tc.service.Wait(ctx, id)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusAccepted, task)
+	case errors.Is(err, context.Canceled):
+		// клиент ушёл, отвечать некому
+	case err != nil:
+		writeServiceError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, task)
+	}
+}
+
+func (tc *TaskController) CancelTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing id parameter")
+		return
+	}
+
+	task, err := tc.service.Cancel(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, apperrors.ErrConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 func (tc *TaskController) GetTaskList(w http.ResponseWriter, r *http.Request) {
-	tasks := tc.service.List()
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query, err := parseTaskQuery(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := tc.service.Query(query)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidData):
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err.Error())
 	}
 }
+
+func parseTaskQuery(values url.Values) (model.TaskQuery, error) {
+	q := model.TaskQuery{
+		Types:    splitList(values["type"]),
+		IDPrefix: values.Get("id_prefix"),
+		Sort:     model.TaskSort(values.Get("sort")),
+		Cursor:   values.Get("cursor"),
+	}
+
+	for _, status := range splitList(values["status"]) {
+		q.Statuses = append(q.Statuses, model.TaskStatus(status))
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return q, fmt.Errorf("invalid limit parameter")
+		}
+		q.Limit = limit
+	}
+
+	for name, dst := range map[string]**time.Time{
+		"created_after":   &q.CreatedAfter,
+		"created_before":  &q.CreatedBefore,
+		"finished_after":  &q.FinishedAfter,
+		"finished_before": &q.FinishedBefore,
+	} {
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return q, fmt.Errorf("invalid %s parameter: expected RFC3339", name)
+		}
+		*dst = &t
+	}
+
+	return q, nil
+}
+
+// splitList поддерживает и повторяющиеся параметры, и значения через запятую.
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
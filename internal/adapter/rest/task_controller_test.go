@@ -1,20 +1,27 @@
 package rest_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/folivorra/task_queue/internal/adapter/rest"
 	"github.com/folivorra/task_queue/internal/adapter/workerpool"
 	"github.com/folivorra/task_queue/internal/model"
 	"github.com/folivorra/task_queue/internal/repository/inmemory"
 	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/clock"
+	"github.com/folivorra/task_queue/pkg/metrics"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func setupTestServer(t *testing.T) (*httptest.Server, *usecase.TaskService, *workerpool.WorkerPool, context.CancelFunc) {
@@ -28,19 +35,42 @@ func setupTestServer(t *testing.T) (*httptest.Server, *usecase.TaskService, *wor
 
 	taskRepo := inmemory.NewTaskInMemoryRepo()
 	taskService := usecase.NewTaskService(taskRepo)
+	taskService.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	taskService.Register("echo", func(ctx context.Context, task *model.Task) (any, error) {
+		return map[string]string{"echo": task.Payload}, nil
+	})
+	taskService.Register("blocking", func(ctx context.Context, task *model.Task) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	taskService.Register("broken", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, errors.New("downstream unavailable")
+	})
 
-	taskQueue := make(chan *model.Task, 10)
+	taskQueue := workerpool.NewPriorityQueue(10, 0)
 	retryQueue := make(chan *model.Task, 10)
 	wg := &sync.WaitGroup{}
-	wp := workerpool.NewWorkerPool(taskService, 2, taskQueue, retryQueue, wg, logger)
+	deadLetterService := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), taskService)
+	wp := workerpool.NewWorkerPool(taskService, deadLetterService, 2, taskQueue, retryQueue, workerpool.Admission{}, wg, logger)
 	wp.Run(ctx)
 
-	taskController := rest.NewTaskController(taskService, wp)
+	taskController := rest.NewTaskController(taskService, wp, usecase.NewIdempotencyService(inmemory.NewIdempotencyInMemoryRepo(), time.Hour, clock.Real{}))
+	deadLetterController := rest.NewDeadLetterController(deadLetterService, wp)
+	eventController := rest.NewEventController(taskService.Events())
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/enqueue", taskController.Enqueue)
+	mux.HandleFunc("/enqueue/batch", taskController.EnqueueBatch)
 	mux.HandleFunc("/tasks", taskController.GetTaskList)
 	mux.HandleFunc("/healthz", taskController.Healthcheck)
+	mux.HandleFunc("/task", taskController.GetTask)
+	mux.HandleFunc("/task/cancel", taskController.CancelTask)
+	mux.HandleFunc("/task/wait", taskController.WaitTask)
+	mux.HandleFunc("/deadletters", deadLetterController.DeadLetters)
+	mux.HandleFunc("/deadletters/requeue", deadLetterController.Requeue)
+	mux.HandleFunc("/events", eventController.Events)
 
 	server := httptest.NewServer(mux)
 
@@ -54,6 +84,7 @@ func TestEnqueueEndpoint(t *testing.T) {
 
 	task := model.CreateTaskRequest{
 		ID:         "test1",
+		Type:       "noop",
 		Payload:    "payload1",
 		MaxRetries: 3,
 	}
@@ -80,6 +111,83 @@ func TestEnqueueEndpoint(t *testing.T) {
 	wp.Shutdown()
 }
 
+func TestEnqueueEndpoint_UnknownType(t *testing.T) {
+	server, _, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	body, _ := json.Marshal(model.CreateTaskRequest{
+		ID:   "test1",
+		Type: "unknown",
+	})
+
+	resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestEnqueueEndpoint_InvalidCallbackURL(t *testing.T) {
+	server, _, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	for _, callback := range []string{"not a url", "ftp://example.com/hook", "/relative"} {
+		body, _ := json.Marshal(model.CreateTaskRequest{ID: "cb", Type: "noop", CallbackURL: callback})
+
+		resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("callback_url %q: expected 400, got %d", callback, resp.StatusCode)
+		}
+	}
+}
+
+func TestEnqueueEndpoint_Delayed(t *testing.T) {
+	server, _, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	body := []byte(`{"id":"delayed","type":"noop","delay":"1h"}`)
+	resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/tasks")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var page model.TaskPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	got := page.Tasks
+
+	if len(got) != 1 || got[0].Status != model.StatusScheduled || got[0].RunAt == nil {
+		t.Fatalf("expected scheduled task with run_at, got %+v", got)
+	}
+	if until := time.Until(*got[0].RunAt); until < 59*time.Minute {
+		t.Errorf("unexpected run_at: %s", got[0].RunAt)
+	}
+}
+
 func TestGetTasksEndpoint(t *testing.T) {
 	server, taskService, wp, cancel := setupTestServer(t)
 	defer server.Close()
@@ -87,8 +195,8 @@ func TestGetTasksEndpoint(t *testing.T) {
 
 	// Создаём несколько задач
 	tasks := []*model.Task{
-		{ID: "task1", Payload: "p1", MaxRetries: 3},
-		{ID: "task2", Payload: "p2", MaxRetries: 2},
+		{ID: "task1", Type: "noop", Payload: "p1", MaxRetries: 3},
+		{ID: "task2", Type: "noop", Payload: "p2", MaxRetries: 2},
 	}
 	for _, task := range tasks {
 		if err := taskService.Save(task); err != nil {
@@ -106,10 +214,11 @@ func TestGetTasksEndpoint(t *testing.T) {
 		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
 
-	var got []*model.Task
-	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+	var page model.TaskPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
+	got := page.Tasks
 
 	if len(got) != len(tasks) {
 		t.Errorf("expected %d tasks, got %d", len(tasks), len(got))
@@ -118,27 +227,670 @@ func TestGetTasksEndpoint(t *testing.T) {
 	wp.Shutdown()
 }
 
-func TestHealthcheckEndpoint(t *testing.T) {
-	server, _, _, cancel := setupTestServer(t)
+func TestGetTasksEndpoint_SortByPriority(t *testing.T) {
+	server, taskService, _, cancel := setupTestServer(t)
 	defer server.Close()
 	defer cancel()
 
-	resp, err := http.Get(server.URL + "/healthz")
+	for _, task := range []*model.Task{
+		{ID: "low", Type: "noop", Priority: 1},
+		{ID: "high", Type: "noop", Priority: 50},
+		{ID: "mid", Type: "noop", Priority: 10},
+	} {
+		if err := taskService.Save(task); err != nil {
+			t.Fatalf("failed to save task: %v", err)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/tasks?sort=priority")
 	if err != nil {
 		t.Fatalf("request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
+	var page model.TaskPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	got := page.Tasks
+
+	if len(got) != 3 || got[0].ID != "high" || got[1].ID != "mid" || got[2].ID != "low" {
+		t.Errorf("unexpected order: %v %v %v", got[0].ID, got[1].ID, got[2].ID)
+	}
+}
+
+func TestDeadLetterEndpoints(t *testing.T) {
+	server, _, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	body := []byte(`{"id":"dead","type":"broken","max_retries":1}`)
+	resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var dls []*model.DeadLetter
+	deadline := time.Now().Add(2 * time.Second)
+	for len(dls) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		resp, err = http.Get(server.URL + "/deadletters")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&dls)
+		resp.Body.Close()
+	}
+	if len(dls) != 1 || dls[0].Task.ID != "dead" || len(dls[0].Errors) != 1 {
+		t.Fatalf("unexpected dead letters: %+v", dls)
+	}
+
+	resp, err = http.Post(server.URL+"/deadletters/requeue?all=true", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var requeued model.RequeueResult
+	_ = json.NewDecoder(resp.Body).Decode(&requeued)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(requeued.Requeued) != 1 || requeued.Requeued[0].ID != "dead" || len(requeued.Failed) != 0 {
+		t.Fatalf("unexpected requeue response: %d %+v", resp.StatusCode, requeued)
+	}
+
+	// задача снова упадёт и вернётся в dead-letter, после чего чистим очередь
+	time.Sleep(200 * time.Millisecond)
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/deadletters", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var purged map[string]int
+	_ = json.NewDecoder(resp.Body).Decode(&purged)
+	resp.Body.Close()
+	if purged["purged"] != 1 {
+		t.Errorf("expected 1 purged dead letter, got %v", purged)
+	}
+}
+
+func TestCancelTaskEndpoint(t *testing.T) {
+	server, taskService, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	body := []byte(`{"id":"long","type":"blocking","max_retries":3}`)
+	resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if task, _ := taskService.Get("long"); task.Status == model.StatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err = http.Post(server.URL+"/task/cancel?id=long", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
 
-	var data map[string]string
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	time.Sleep(200 * time.Millisecond)
+	task, _ := taskService.Get("long")
+	if task.Status != model.StatusCancelled || task.Attempts != 1 {
+		t.Errorf("cancelled task must not be retried, got status %s attempts %d", task.Status, task.Attempts)
+	}
+
+	resp, err = http.Post(server.URL+"/task/cancel?id=long", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for already cancelled task, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetTasksEndpoint_FilterAndPaginate(t *testing.T) {
+	server, taskService, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	for _, task := range []*model.Task{
+		{ID: "report-1", Type: "noop", RunAt: ptrTime(time.Now().Add(time.Hour))},
+		{ID: "report-2", Type: "noop", RunAt: ptrTime(time.Now().Add(time.Hour))},
+		{ID: "report-3", Type: "noop", RunAt: ptrTime(time.Now().Add(time.Hour))},
+		{ID: "email-1", Type: "noop", RunAt: ptrTime(time.Now().Add(time.Hour))},
+		{ID: "report-4", Type: "blocking", RunAt: ptrTime(time.Now().Add(time.Hour))},
+	} {
+		if err := taskService.Save(task); err != nil {
+			t.Fatalf("failed to save task: %v", err)
+		}
+	}
+
+	var (
+		ids    []string
+		cursor string
+		pages  int
+	)
+	for {
+		resp, err := http.Get(server.URL + "/tasks?status=scheduled&type=noop&id_prefix=report-&sort=id&limit=2&cursor=" + cursor)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+
+		var page model.TaskPage
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		resp.Body.Close()
+
+		pages++
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if pages != 2 || strings.Join(ids, ",") != "report-1,report-2,report-3" {
+		t.Errorf("unexpected pages=%d ids=%v", pages, ids)
+	}
+
+	for _, query := range []string{"sort=unknown", "limit=-1", "cursor=garbage", "created_after=yesterday"} {
+		resp, err := http.Get(server.URL + "/tasks?" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func TestEnqueueEndpoint_RejectsWhenSaturated(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	taskService := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	taskService.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+
+	// пул не запущен: первая задача занимает единственное место в очереди
+	wp := workerpool.NewWorkerPool(taskService, usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), taskService), 1,
+		workerpool.NewPriorityQueue(1, 0), make(chan *model.Task, 1),
+		workerpool.Admission{Policy: workerpool.AdmitReject, RetryAfter: 1500 * time.Millisecond}, &sync.WaitGroup{}, logger)
+
+	taskController := rest.NewTaskController(taskService, wp, usecase.NewIdempotencyService(inmemory.NewIdempotencyInMemoryRepo(), time.Hour, clock.Real{}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enqueue", taskController.Enqueue)
+	mux.HandleFunc("/task", taskController.GetTask)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	enqueue := func(id string) *http.Response {
+		body := []byte(`{"id":"` + id + `","type":"noop"}`)
+		resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := enqueue("first"); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+
+	resp := enqueue("second")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", resp.StatusCode)
 	}
+	if got := resp.Header.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+
+	resp, err := http.Get(server.URL + "/task?id=second")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("rejected task must be rolled back, got %d", resp.StatusCode)
+	}
+
+	wp.Shutdown()
+	if resp := enqueue("third"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after shutdown, got %d", resp.StatusCode)
+	}
+}
 
-	if data["status"] != "ok" {
-		t.Errorf("expected status 'ok', got %v", data["status"])
+func TestEnqueueEndpoint_IdempotencyKey(t *testing.T) {
+	server, taskService, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	enqueue := func(key, body string) (*http.Response, model.Task) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/enqueue", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var task model.Task
+		_ = json.NewDecoder(resp.Body).Decode(&task)
+		return resp, task
+	}
+
+	// id не передан — сервер генерирует его сам
+	resp, first := enqueue("key-1", `{"type":"noop","payload":"p"}`)
+	if resp.StatusCode != http.StatusCreated || first.ID == "" {
+		t.Fatalf("expected 201 with generated id, got %d %+v", resp.StatusCode, first)
+	}
+
+	// тот же запрос с другим порядком полей — повтор исходного ответа
+	resp, replayed := enqueue("key-1", `{"payload":"p", "type":"noop"}`)
+	if resp.StatusCode != http.StatusCreated || replayed.ID != first.ID {
+		t.Fatalf("expected replay of %s, got %d %+v", first.ID, resp.StatusCode, replayed)
+	}
+	if resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("replayed response must be marked")
+	}
+
+	if resp, _ := enqueue("key-1", `{"type":"noop","payload":"other"}`); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a different body, got %d", resp.StatusCode)
+	}
+
+	if got := len(taskService.List()); got != 1 {
+		t.Errorf("expected exactly one task, got %d", got)
+	}
+
+	// неуспешный запрос ключ не занимает
+	if resp, _ := enqueue("key-2", `{"type":"unknown"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	if resp, _ := enqueue("key-2", `{"type":"noop"}`); resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected 201 after a failed attempt with the same key, got %d", resp.StatusCode)
+	}
+}
+
+func TestEnqueueBatchEndpoint(t *testing.T) {
+	server, taskService, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	post := func(query, contentType, body string) (*http.Response, model.BatchResult) {
+		resp, err := http.Post(server.URL+"/enqueue/batch"+query, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var result model.BatchResult
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+	statuses := func(result model.BatchResult) string {
+		var out []string
+		for _, item := range result.Results {
+			out = append(out, string(item.Status))
+		}
+		return strings.Join(out, ",")
+	}
+
+	_ = taskService.Save(&model.Task{ID: "existing", Type: "noop", RunAt: ptrTime(time.Now().Add(time.Hour))})
+
+	t.Run("partial", func(t *testing.T) {
+		resp, result := post("", "application/json", `[
+			{"id":"p1","type":"noop"},
+			{"id":"existing","type":"noop"},
+			{"id":"p2","type":"unknown"},
+			{"type":"noop"}
+		]`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		if got := statuses(result); got != "created,conflict,invalid,created" || result.Created != 2 {
+			t.Errorf("unexpected results %s (created=%d)", got, result.Created)
+		}
+		if result.Results[3].Task == nil || result.Results[3].Task.ID == "" {
+			t.Error("created item must carry the task with a generated id")
+		}
+	})
+
+	t.Run("atomic failure creates nothing", func(t *testing.T) {
+		resp, result := post("?mode=atomic", "application/json", `[
+			{"id":"a1","type":"noop"},
+			{"id":"a1","type":"noop"},
+			{"id":"a2","type":"noop","priority":1000}
+		]`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", resp.StatusCode)
+		}
+		if got := statuses(result); got != "skipped,conflict,invalid" {
+			t.Errorf("unexpected results %s", got)
+		}
+		if _, err := taskService.Get("a1"); err == nil {
+			t.Error("atomic batch must not create any task on failure")
+		}
+	})
+
+	t.Run("atomic ndjson", func(t *testing.T) {
+		resp, result := post("?mode=atomic", "application/x-ndjson", "{\"id\":\"n1\",\"type\":\"noop\"}\n{\"id\":\"n2\",\"type\":\"noop\"}\n")
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", resp.StatusCode)
+		}
+		if got := statuses(result); got != "created,created" || result.Created != 2 {
+			t.Errorf("unexpected results %s", got)
+		}
+	})
+
+	t.Run("atomic batch larger than queue", func(t *testing.T) {
+		items := make([]string, 11)
+		for i := range items {
+			items[i] = `{"id":"big` + strconv.Itoa(i) + `","type":"noop"}`
+		}
+		resp, result := post("?mode=atomic", "application/json", "["+strings.Join(items, ",")+"]")
+		if resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected 413, got %d", resp.StatusCode)
+		}
+		if resp.Header.Get("Retry-After") != "" {
+			t.Error("oversized batch must not be retried")
+		}
+		if result.Created != 0 || !strings.Contains(result.Results[0].Error, "batch exceeds queue capacity") {
+			t.Errorf("unexpected result %+v", result.Results[0])
+		}
+		if _, err := taskService.Get("big0"); err == nil {
+			t.Error("oversized batch must not create any task")
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		if resp, _ := post("", "application/json", `{"id":"x"}`); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for a non-array body, got %d", resp.StatusCode)
+		}
+		resp, result := post("", "application/x-ndjson", "{\"id\":\"m1\",\"type\":\"noop\"}\n{broken\n")
+		if resp.StatusCode != http.StatusBadRequest || result.Created != 1 || result.Error == "" {
+			t.Errorf("expected 400 with the items read before the error, got %d %+v", resp.StatusCode, result)
+		}
+	})
+}
+
+func TestEventsEndpoint(t *testing.T) {
+	server, _, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	ctx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+
+	subscribe := func(query string, header http.Header) *bufio.Reader {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events"+query, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("subscribe failed: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("unexpected content type %q", ct)
+		}
+		return bufio.NewReader(resp.Body)
+	}
+	// next возвращает id и имя следующего события, пропуская комментарии
+	next := func(r *bufio.Reader) (id, name string) {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			switch line = strings.TrimSuffix(line, "\n"); {
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case line == "" && name != "":
+				return id, name
+			}
+		}
+	}
+
+	stream := subscribe("?id=sse1", nil)
+
+	body, _ := json.Marshal(model.CreateTaskRequest{ID: "sse1", Type: "noop"})
+	resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var ids []string
+	for _, want := range []string{"queued", "running", "done"} {
+		id, name := next(stream)
+		if name != want {
+			t.Fatalf("got event %q, want %q", name, want)
+		}
+		ids = append(ids, id)
+	}
+
+	resumed := subscribe("?id=sse1", http.Header{"Last-Event-Id": {ids[0]}})
+	for _, want := range ids[1:] {
+		if id, _ := next(resumed); id != want {
+			t.Errorf("resume: got event id %s, want %s", id, want)
+		}
+	}
+
+	if _, name := next(subscribe("?last_event_id=100000", nil)); name != "reset" {
+		t.Errorf("unknown last event id must produce reset, got %q", name)
+	}
+}
+
+func TestWaitTaskEndpoint(t *testing.T) {
+	server, taskService, wp, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	wait := func(query string) (int, model.Task) {
+		resp, err := http.Get(server.URL + "/task/wait?" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var task model.Task
+		_ = json.NewDecoder(resp.Body).Decode(&task)
+		return resp.StatusCode, task
+	}
+
+	if code, _ := wait("id=missing"); code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", code)
+	}
+	if code, _ := wait("id=x&timeout=1h"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a timeout above the limit, got %d", code)
+	}
+
+	blocking := &model.Task{ID: "wait-blocking", Type: "blocking"}
+	_ = taskService.Save(blocking)
+	wp.PushToQueue(blocking)
+
+	if code, task := wait("id=wait-blocking&timeout=50ms"); code != http.StatusAccepted || task.ID != "wait-blocking" {
+		t.Errorf("expected 202 with the unfinished task on timeout, got %d %+v", code, task)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = taskService.Cancel("wait-blocking")
+	}()
+	if code, task := wait("id=wait-blocking&timeout=5s"); code != http.StatusOK || task.Status != model.StatusCancelled {
+		t.Errorf("expected 200 with a cancelled task, got %d %s", code, task.Status)
+	}
+
+	body, _ := json.Marshal(model.CreateTaskRequest{ID: "wait-echo", Type: "echo", Payload: "hi"})
+	resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+	resp.Body.Close()
+
+	code, task := wait("id=wait-echo")
+	if code != http.StatusOK || task.Status != model.StatusDone || string(task.Result) != `{"echo":"hi"}` {
+		t.Errorf("expected 200 with the finished task, got %d %+v", code, task)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := usecase.NewMetrics(registry)
+	m.Enqueued.Inc("email")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", rest.NewMetricsController(registry).Metrics)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		`taskqueue_tasks_enqueued_total{type="email"} 1`,
+		"# TYPE taskqueue_busy_workers gauge",
+		"# TYPE taskqueue_handler_duration_seconds histogram",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output does not contain %q", want)
+		}
+	}
+}
+
+func TestGetTaskEndpoint_ResultAndHistory(t *testing.T) {
+	server, _, _, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	body := []byte(`{"id":"echo1","type":"echo","payload":"hello"}`)
+	resp, err := http.Post(server.URL+"/enqueue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var got model.Task
+	deadline := time.Now().Add(time.Second)
+	for got.Status != model.StatusDone && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		resp, err = http.Get(server.URL + "/task?id=echo1")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+	}
+
+	if got.Status != model.StatusDone || string(got.Result) != `{"echo":"hello"}` {
+		t.Fatalf("unexpected task: %+v", got)
+	}
+	if len(got.History) != 1 || got.History[0].WorkerID == 0 || got.History[0].FinishedAt == nil {
+		t.Errorf("unexpected history: %+v", got.History)
+	}
+	if got.CreatedAt.IsZero() || got.QueuedAt == nil || got.StartedAt == nil || got.FinishedAt == nil {
+		t.Errorf("lifecycle timestamps must be exposed: %+v", got)
+	}
+}
+
+func TestHealthcheckEndpoint(t *testing.T) {
+	server, _, wp, cancel := setupTestServer(t)
+	defer server.Close()
+	defer cancel()
+
+	health := func() (data struct {
+		Status string           `json:"status"`
+		Paused model.PauseState `json:"paused"`
+	}) {
+		resp, err := http.Get(server.URL + "/healthz")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return data
+	}
+
+	data := health()
+	if data.Status != "ok" {
+		t.Errorf("expected status 'ok', got %v", data.Status)
+	}
+	if data.Paused.Active() {
+		t.Errorf("expected no pause, got %+v", data.Paused)
+	}
+
+	wp.SetPaused(model.PauseState{Types: []string{"echo"}})
+	data = health()
+	if data.Status != "ok" || !data.Paused.Paused("echo") || data.Paused.Paused("noop") {
+		t.Errorf("expected echo to be reported as paused, got %+v", data)
+	}
+}
+
+func TestAdminWorkersEndpoint_Bounds(t *testing.T) {
+	taskService := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	wp := workerpool.NewWorkerPool(taskService, usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), taskService), 1,
+		workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+	wp.SetMaxWorkers(2)
+
+	adminController := rest.NewAdminController(wp, usecase.NewPauseService(inmemory.NewPauseInMemoryRepo()))
+	server := httptest.NewServer(http.HandlerFunc(adminController.Workers))
+	defer server.Close()
+
+	resize := func(n int) *http.Response {
+		req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(`{"workers":`+strconv.Itoa(n)+`}`))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, n := range []int{0, 3} {
+		if resp := resize(n); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("workers=%d: expected 400, got %d", n, resp.StatusCode)
+		}
+	}
+	if resp := resize(2); resp.StatusCode != http.StatusOK {
+		t.Errorf("workers=2: expected 200, got %d", resp.StatusCode)
+	}
+	if got := wp.Workers(); got.Target != 2 || got.Max != 2 {
+		t.Errorf("unexpected pool size %+v", got)
 	}
 }
@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/usecase"
+)
+
+const (
+	SignatureHeader = "X-Signature-256"
+	// DefaultWorkers — число одновременных доставок по умолчанию.
+	DefaultWorkers = 4
+)
+
+type Config struct {
+	// Secret — ключ подписи HMAC-SHA256; пустой — запросы не подписываются.
+	Secret string
+	// Timeout ограничивает одну попытку доставки.
+	Timeout time.Duration
+	// MaxAttempts — сколько раз пытаться доставить webhook, прежде чем
+	// отметить его failed.
+	MaxAttempts int
+	// BaseDelay и MaxDelay задают экспоненциальную паузу между попытками;
+	// она не связана с повторами самой задачи.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Workers ограничивает число одновременных запросов; медленный адрес
+	// занимает воркера только на время попытки, а не на время backoff.
+	Workers int
+}
+
+// Dispatcher отправляет итоговую задачу POST-запросом на её callback_url
+// и записывает попытки доставки в задачу. Попытки выполняют Workers
+// воркеров, а доставки, ждущие backoff, лежат в очереди с таймером.
+type Dispatcher struct {
+	service *usecase.TaskService
+	client  *http.Client
+	cfg     Config
+	queue   *queue
+	wg      *sync.WaitGroup
+	logger  *slog.Logger
+}
+
+func NewDispatcher(service *usecase.TaskService, cfg Config, wg *sync.WaitGroup, logger *slog.Logger) *Dispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+
+	return &Dispatcher{
+		service: service,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		cfg:     cfg,
+		queue:   newQueue(),
+		wg:      wg,
+		logger:  logger,
+	}
+}
+
+// Sign возвращает значение заголовка подписи для тела запроса.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify ставит webhook завершённой задачи в очередь доставки. Подходит для
+// TaskService.OnFinished: не блокируется, а очередь не ограничена, поэтому
+// доставка не теряется и без перезапуска.
+func (d *Dispatcher) Notify(task model.Task) {
+	if task.CallbackURL == "" {
+		return
+	}
+
+	// нумерация продолжается, если задача уже завершалась раньше (requeue)
+	d.queue.push(&job{task: task, attempt: 1, first: len(task.Deliveries) + 1})
+}
+
+// Resume ставит в очередь webhooks, доставка которых прервалась остановкой сервиса.
+func (d *Dispatcher) Resume() int {
+	n := 0
+	for _, task := range d.service.List() {
+		if task.CallbackStatus == model.CallbackPending {
+			d.Notify(*task)
+			n++
+		}
+	}
+	return n
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.queue.runTimer(ctx)
+	}()
+
+	for range d.cfg.Workers {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				j, ok := d.queue.pop(ctx)
+				if !ok {
+					d.logger.Info("webhook dispatcher context done")
+					return
+				}
+				d.deliver(ctx, j)
+			}
+		}()
+	}
+}
+
+// deliver выполняет одну попытку доставки; неудачную, если попытки не
+// исчерпаны, откладывает в очередь на backoff.
+func (d *Dispatcher) deliver(ctx context.Context, j *job) {
+	task := j.task
+	if j.body == nil {
+		body, err := json.Marshal(task)
+		if err != nil {
+			d.logger.Error("failed to encode webhook",
+				slog.String("task_id", task.ID),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		j.body = body
+	}
+
+	delivery := model.Delivery{Number: j.first + j.attempt - 1, At: d.service.Now()}
+
+	code, err := d.post(ctx, task, j.body, delivery.Number)
+	if ctx.Err() != nil {
+		// остановка сервиса: webhook остаётся pending до Resume
+		return
+	}
+
+	status := model.CallbackPending
+	switch {
+	case err != nil:
+		delivery.Error = err.Error()
+	default:
+		delivery.StatusCode = code
+		if code >= 200 && code < 300 {
+			status = model.CallbackDelivered
+		} else {
+			delivery.Error = fmt.Sprintf("unexpected status %d", code)
+		}
+	}
+	if status != model.CallbackDelivered && j.attempt == d.cfg.MaxAttempts {
+		status = model.CallbackFailed
+	}
+
+	if err := d.service.RecordDelivery(task.ID, delivery, status); err != nil {
+		d.logger.Error("failed to record webhook delivery",
+			slog.String("task_id", task.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	switch status {
+	case model.CallbackDelivered:
+		d.logger.Info("webhook delivered",
+			slog.String("task_id", task.ID),
+			slog.Int("attempt", delivery.Number),
+		)
+	case model.CallbackFailed:
+		d.logger.Warn("webhook delivery failed",
+			slog.String("task_id", task.ID),
+			slog.String("error", delivery.Error),
+		)
+	default:
+		j.at = time.Now().Add(d.backoff(j.attempt))
+		j.attempt++
+		d.queue.delay(j)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, task model.Task, body []byte, number int) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Task-Id", task.ID)
+	req.Header.Set("X-Delivery-Attempt", strconv.Itoa(number))
+	if d.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(d.cfg.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseDelay << (attempt - 1)
+	if delay <= 0 || (d.cfg.MaxDelay > 0 && delay > d.cfg.MaxDelay) {
+		delay = d.cfg.MaxDelay
+	}
+	return delay
+}
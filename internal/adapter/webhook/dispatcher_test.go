@@ -0,0 +1,168 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/adapter/webhook"
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/inmemory"
+	"github.com/folivorra/task_queue/internal/usecase"
+)
+
+func setupDispatcher(t *testing.T, cfg webhook.Config) (*usecase.TaskService, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return "ok", nil
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dispatcher := webhook.NewDispatcher(service, cfg, &sync.WaitGroup{}, logger)
+	service.OnFinished(dispatcher.Notify)
+	dispatcher.Run(ctx)
+
+	return service, cancel
+}
+
+func runTask(t *testing.T, service *usecase.TaskService, task *model.Task) {
+	if err := service.Save(task); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := service.HandleTask(context.Background(), task); err != nil {
+		t.Fatalf("handle failed: %v", err)
+	}
+}
+
+func waitCallback(t *testing.T, service *usecase.TaskService, id string) *model.Task {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		task, _ := service.Get(id)
+		if task.CallbackStatus != model.CallbackPending {
+			return task
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("webhook for %s was not settled", id)
+	return nil
+}
+
+func TestDispatcher_RetriesAndSigns(t *testing.T) {
+	var (
+		calls    atomic.Int32
+		received = make(chan model.Task, 1)
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(webhook.SignatureHeader); got != webhook.Sign("secret", body) {
+			t.Errorf("bad signature %q", got)
+		}
+		if r.Header.Get("X-Delivery-Attempt") != "2" {
+			t.Errorf("unexpected attempt header %q", r.Header.Get("X-Delivery-Attempt"))
+		}
+
+		var task model.Task
+		_ = json.Unmarshal(body, &task)
+		received <- task
+	}))
+	defer receiver.Close()
+
+	service, cancel := setupDispatcher(t, webhook.Config{Secret: "secret", MaxAttempts: 3, BaseDelay: 10 * time.Millisecond})
+	defer cancel()
+
+	runTask(t, service, &model.Task{ID: "t1", Type: "noop", CallbackURL: receiver.URL})
+
+	task := waitCallback(t, service, "t1")
+	if task.CallbackStatus != model.CallbackDelivered {
+		t.Fatalf("callback status = %s, want %s", task.CallbackStatus, model.CallbackDelivered)
+	}
+	if len(task.Deliveries) != 2 || task.Deliveries[0].StatusCode != http.StatusServiceUnavailable || task.Deliveries[1].StatusCode != http.StatusOK {
+		t.Errorf("unexpected deliveries %+v", task.Deliveries)
+	}
+
+	got := <-received
+	if got.ID != "t1" || got.Status != model.StatusDone || string(got.Result) != `"ok"` {
+		t.Errorf("webhook must carry the final task, got %+v", got)
+	}
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	service, cancel := setupDispatcher(t, webhook.Config{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	defer cancel()
+
+	runTask(t, service, &model.Task{ID: "t1", Type: "noop", CallbackURL: receiver.URL})
+	runTask(t, service, &model.Task{ID: "t2", Type: "noop"})
+
+	task := waitCallback(t, service, "t1")
+	if task.CallbackStatus != model.CallbackFailed || len(task.Deliveries) != 2 || calls.Load() != 2 {
+		t.Errorf("expected 2 failed deliveries, got %s %+v (calls=%d)", task.CallbackStatus, task.Deliveries, calls.Load())
+	}
+
+	if task, _ := service.Get("t2"); task.CallbackStatus != "" || len(task.Deliveries) != 0 {
+		t.Errorf("task without callback_url must not be delivered, got %+v", task)
+	}
+}
+
+func TestDispatcher_BoundedWorkers(t *testing.T) {
+	var (
+		inFlight, peak atomic.Int32
+		calls          atomic.Int32
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		// каждый второй запрос падает, и доставка уходит ждать backoff
+		if calls.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	service, cancel := setupDispatcher(t, webhook.Config{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, Workers: 2})
+	defer cancel()
+
+	ids := make([]string, 10)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("t%d", i)
+		runTask(t, service, &model.Task{ID: ids[i], Type: "noop", CallbackURL: receiver.URL})
+	}
+
+	for _, id := range ids {
+		if task := waitCallback(t, service, id); task.CallbackStatus != model.CallbackDelivered {
+			t.Errorf("webhook for %s: status %s, want %s", id, task.CallbackStatus, model.CallbackDelivered)
+		}
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("%d deliveries ran at once, want at most 2 workers", got)
+	}
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+// job — доставка webhook одной задачи. attempt — номер следующей попытки
+// в рамках этой доставки (с 1), first — её сквозной номер в Deliveries.
+type job struct {
+	task    model.Task
+	body    []byte
+	attempt int
+	first   int
+	at      time.Time
+}
+
+type jobHeap []*job
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h jobHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x any) { *h = append(*h, x.(*job)) }
+
+func (h *jobHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// queue — очередь доставок: готовые выдаются воркерам в порядке поступления,
+// ожидающие повторной попытки лежат в min-heap по времени, который
+// обслуживает одна горутина с одним таймером. Память на ожидающую доставку —
+// запись в куче, а не спящая горутина.
+type queue struct {
+	mu      sync.Mutex
+	ready   []*job
+	delayed jobHeap
+	// readyCh сигналит воркерам о готовых доставках, wake — таймеру о новых
+	// отложенных
+	readyCh chan struct{}
+	wake    chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{
+		readyCh: make(chan struct{}, 1),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// push не блокируется: очередь не ограничена, доставка не теряется.
+func (q *queue) push(j *job) {
+	q.mu.Lock()
+	q.ready = append(q.ready, j)
+	q.mu.Unlock()
+
+	signal(q.readyCh)
+}
+
+// pop ждёт готовую доставку, пока не отменён ctx.
+func (q *queue) pop(ctx context.Context) (*job, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.ready) > 0 {
+			j := q.ready[0]
+			q.ready[0] = nil
+			q.ready = q.ready[1:]
+			more := len(q.ready) > 0
+			q.mu.Unlock()
+
+			if more {
+				signal(q.readyCh)
+			}
+			return j, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-q.readyCh:
+		}
+	}
+}
+
+// delay откладывает доставку до j.at.
+func (q *queue) delay(j *job) {
+	q.mu.Lock()
+	heap.Push(&q.delayed, j)
+	q.mu.Unlock()
+
+	signal(q.wake)
+}
+
+// runTimer переносит отложенные доставки в готовые, когда подходит их время.
+func (q *queue) runTimer(ctx context.Context) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		q.mu.Lock()
+		var wait time.Duration = -1
+		if q.delayed.Len() > 0 {
+			wait = time.Until(q.delayed[0].at)
+			if wait <= 0 {
+				j := heap.Pop(&q.delayed).(*job)
+				q.ready = append(q.ready, j)
+				q.mu.Unlock()
+				signal(q.readyCh)
+				continue
+			}
+		}
+		q.mu.Unlock()
+
+		var timerC <-chan time.Time
+		if wait > 0 {
+			timer.Reset(wait)
+			timerC = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-timerC:
+		}
+		timer.Stop()
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
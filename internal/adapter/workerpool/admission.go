@@ -0,0 +1,172 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type AdmissionPolicy string
+
+const (
+	// AdmitReject сразу отказывает, если очередь заполнена.
+	AdmitReject AdmissionPolicy = "reject"
+	// AdmitWait ждёт свободного места не дольше Timeout (0 — пока жив запрос).
+	AdmitWait AdmissionPolicy = "wait"
+	// AdmitOverflow складывает задачи в буфер переполнения, откуда они
+	// переходят в очередь по мере освобождения места.
+	AdmitOverflow AdmissionPolicy = "overflow"
+)
+
+// Admission — правила приёма новых задач в очередь воркеров.
+type Admission struct {
+	Policy AdmissionPolicy
+	// Timeout — сколько ждать места при политике wait.
+	Timeout time.Duration
+	// OverflowLimit ограничивает буфер переполнения; <= 0 — без ограничения.
+	OverflowLimit int
+	// RetryAfter — через сколько клиенту стоит повторить отклонённый запрос.
+	RetryAfter time.Duration
+}
+
+// ErrBatchTooLarge — в пачке больше готовых задач, чем очередь вместе с
+// буфером переполнения может принять за раз: повтор запроса не поможет.
+var ErrBatchTooLarge = errors.New("batch exceeds queue capacity")
+
+func ParseAdmissionPolicy(s string) (AdmissionPolicy, error) {
+	switch p := AdmissionPolicy(s); p {
+	case AdmitReject, AdmitWait, AdmitOverflow:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: unknown admission policy %q", apperrors.ErrInvalidData, s)
+	}
+}
+
+func (wp *WorkerPool) RetryAfter() time.Duration {
+	return wp.admission.RetryAfter
+}
+
+// Admit принимает новую задачу согласно политике. Ошибка ErrOverloaded
+// означает, что очередь переполнена, ErrUnavailable — что пул остановлен.
+func (wp *WorkerPool) Admit(ctx context.Context, task *model.Task) error {
+	return wp.AdmitAll(ctx, []*model.Task{task})
+}
+
+// AdmitAll принимает все задачи или ни одной.
+func (wp *WorkerPool) AdmitAll(ctx context.Context, tasks []*model.Task) error {
+	if wp.draining.Load() {
+		return fmt.Errorf("%w: worker pool is shutting down", apperrors.ErrUnavailable)
+	}
+
+	var scheduled, ready []*model.Task
+	for _, task := range tasks {
+		if task.Status == model.StatusScheduled && task.RunAt != nil {
+			scheduled = append(scheduled, task)
+			continue
+		}
+		ready = append(ready, task)
+	}
+
+	if limit := wp.batchLimit(); limit > 0 && len(ready) > limit {
+		return fmt.Errorf("%w: %d tasks, at most %d fit", ErrBatchTooLarge, len(ready), limit)
+	}
+
+	var err error
+	switch wp.admission.Policy {
+	case AdmitReject:
+		err = wp.taskQueue.TryPushAll(ready)
+	case AdmitOverflow:
+		err = wp.taskQueue.TryPushAll(ready)
+		if errors.Is(err, ErrQueueFull) {
+			err = wp.spill(ready)
+		}
+	default:
+		if wp.admission.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, wp.admission.Timeout)
+			defer cancel()
+		}
+		err = wp.taskQueue.PushAll(ctx, ready)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrQueueFull
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		return fmt.Errorf("%w: task queue is full", apperrors.ErrOverloaded)
+	case errors.Is(err, ErrQueueClosed):
+		return fmt.Errorf("%w: worker pool is shutting down", apperrors.ErrUnavailable)
+	case err != nil:
+		return err
+	}
+
+	for _, task := range scheduled {
+		wp.Schedule(task)
+	}
+
+	// принятыми считаются только задачи, прошедшие контроль приёма
+	enqueued := wp.service.Metrics().Enqueued
+	for _, task := range tasks {
+		enqueued.Inc(task.Type)
+	}
+
+	return nil
+}
+
+// batchLimit — сколько готовых задач AdmitAll способен принять одной пачкой;
+// 0 — без ограничения. При политике overflow пачка, не вошедшая в очередь,
+// целиком уходит в буфер переполнения.
+func (wp *WorkerPool) batchLimit() int {
+	limit := wp.taskQueue.Cap()
+	if wp.admission.Policy == AdmitOverflow && limit > 0 {
+		if wp.admission.OverflowLimit <= 0 {
+			return 0
+		}
+		limit = max(limit, wp.admission.OverflowLimit)
+	}
+	return limit
+}
+
+func (wp *WorkerPool) spill(tasks []*model.Task) error {
+	wp.overflowMu.Lock()
+	if limit := wp.admission.OverflowLimit; limit > 0 && len(wp.overflow)+len(tasks) > limit {
+		wp.overflowMu.Unlock()
+		return ErrQueueFull
+	}
+	wp.overflow = append(wp.overflow, tasks...)
+	wp.overflowMu.Unlock()
+
+	signal(wp.overflowReady)
+	return nil
+}
+
+// overflowCheck переносит задачи из буфера переполнения в очередь в порядке
+// поступления, блокируясь, пока в очереди нет места.
+func (wp *WorkerPool) overflowCheck(ctx context.Context) {
+	for ctx.Err() == nil {
+		wp.overflowMu.Lock()
+		var task *model.Task
+		if len(wp.overflow) > 0 {
+			task = wp.overflow[0]
+			wp.overflow[0] = nil
+			wp.overflow = wp.overflow[1:]
+		}
+		wp.overflowMu.Unlock()
+
+		if task != nil {
+			wp.push(ctx, task)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-wp.overflowReady:
+		}
+	}
+	wp.logger.Info("overflow worker context done")
+}
@@ -0,0 +1,207 @@
+package workerpool
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/pkg/metrics"
+)
+
+// AutoscaleConfig задаёт границы и пороги автоскейлера. Нулевые поля, кроме
+// Min и Max, получают значения по умолчанию.
+type AutoscaleConfig struct {
+	Min int
+	Max int
+	// Interval — период оценки нагрузки.
+	Interval time.Duration
+	// UpCooldown и DownCooldown — сколько ждать после любого изменения
+	// размера, прежде чем снова увеличивать или уменьшать пул.
+	UpCooldown   time.Duration
+	DownCooldown time.Duration
+	// QueuePerWorker — допустимая глубина очереди на воркера.
+	QueuePerWorker float64
+	// MaxQueueWait — допустимое среднее ожидание задачи в очереди.
+	MaxQueueWait time.Duration
+	// HighUtilization и LowUtilization — доля занятых воркеров (0..1), выше
+	// которой при непустой очереди пул растёт и ниже которой сжимается.
+	HighUtilization float64
+	LowUtilization  float64
+}
+
+func (c AutoscaleConfig) withDefaults() AutoscaleConfig {
+	if c.Min < 1 {
+		c.Min = 1
+	}
+	c.Max = max(c.Max, c.Min)
+	if c.Interval <= 0 {
+		c.Interval = 2 * time.Second
+	}
+	if c.UpCooldown <= 0 {
+		c.UpCooldown = 10 * time.Second
+	}
+	if c.DownCooldown <= 0 {
+		c.DownCooldown = time.Minute
+	}
+	if c.QueuePerWorker <= 0 {
+		c.QueuePerWorker = 2
+	}
+	if c.MaxQueueWait <= 0 {
+		c.MaxQueueWait = time.Second
+	}
+	if c.HighUtilization <= 0 {
+		c.HighUtilization = 0.9
+	}
+	if c.LowUtilization <= 0 {
+		c.LowUtilization = 0.3
+	}
+	return c
+}
+
+// Autoscaler раз в Interval оценивает глубину очереди (без задач на паузе),
+// среднее ожидание задач и загрузку воркеров и меняет размер пула через
+// Resize в пределах [Min, Max]. Пока он включён, ручной Resize действует до
+// следующего решения.
+type Autoscaler struct {
+	pool   *WorkerPool
+	cfg    AutoscaleConfig
+	wg     *sync.WaitGroup
+	logger *slog.Logger
+
+	decisions   *metrics.CounterVec
+	target      *metrics.Gauge
+	utilization *metrics.Gauge
+	queueWait   *metrics.Gauge
+
+	// util — сглаженная загрузка, lastScale — время последнего изменения
+	util      float64
+	lastScale time.Time
+}
+
+func NewAutoscaler(pool *WorkerPool, cfg AutoscaleConfig, registry *metrics.Registry, wg *sync.WaitGroup, logger *slog.Logger) *Autoscaler {
+	return &Autoscaler{
+		pool:        pool,
+		cfg:         cfg.withDefaults(),
+		wg:          wg,
+		logger:      logger,
+		decisions:   registry.NewCounterVec("taskqueue_autoscaler_decisions_total", "Worker pool resizes made by the autoscaler.", "direction"),
+		target:      registry.NewGauge("taskqueue_autoscaler_target_workers", "Worker count chosen by the autoscaler."),
+		utilization: registry.NewGauge("taskqueue_autoscaler_utilization", "Smoothed share of busy workers seen by the autoscaler."),
+		queueWait:   registry.NewGauge("taskqueue_autoscaler_queue_wait_seconds", "Mean queue wait of tasks picked up during the last interval."),
+	}
+}
+
+func (a *Autoscaler) Run(ctx context.Context) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.loop(ctx)
+	}()
+}
+
+func (a *Autoscaler) loop(ctx context.Context) {
+	// стартовый размер приводится к границам сразу
+	current := a.pool.Workers().Target
+	if clamped := min(max(current, a.cfg.Min), a.cfg.Max); clamped != current {
+		a.scale(current, clamped, "out of bounds", autoscaleSample{})
+	}
+	a.target.Set(float64(a.pool.Workers().Target))
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.logger.Info("autoscaler context done")
+			return
+		case now := <-ticker.C:
+			a.evaluate(now)
+		}
+	}
+}
+
+type autoscaleSample struct {
+	depth       int
+	wait        time.Duration
+	utilization float64
+}
+
+func (a *Autoscaler) evaluate(now time.Time) {
+	stats := a.pool.Workers()
+	wait, _ := a.pool.taskQueue.takeWaits()
+	busy := float64(a.pool.busy.Load()) / float64(max(stats.Running, stats.Target, 1))
+	a.util = 0.5*a.util + 0.5*busy
+
+	sample := autoscaleSample{
+		depth:       a.pool.DispatchableLen(),
+		wait:        wait,
+		utilization: a.util,
+	}
+	a.utilization.Set(sample.utilization)
+	a.queueWait.Set(sample.wait.Seconds())
+
+	current := stats.Target
+	next, reason := a.decide(current, sample)
+	switch {
+	case next > current && now.Sub(a.lastScale) >= a.cfg.UpCooldown,
+		next < current && now.Sub(a.lastScale) >= a.cfg.DownCooldown:
+		a.scale(current, next, reason, sample)
+	}
+}
+
+// decide возвращает желаемое число воркеров и причину изменения.
+func (a *Autoscaler) decide(current int, s autoscaleSample) (int, string) {
+	cfg := a.cfg
+
+	var (
+		next   = current
+		reason string
+	)
+	switch {
+	case float64(s.depth) > float64(current)*cfg.QueuePerWorker:
+		next = max(current+1, int(math.Ceil(float64(s.depth)/cfg.QueuePerWorker)))
+		reason = "queue depth"
+	case s.wait > cfg.MaxQueueWait:
+		next = current + 1
+		reason = "queue wait"
+	case s.depth > 0 && s.utilization >= cfg.HighUtilization:
+		next = current + 1
+		reason = "utilization"
+	case s.depth == 0 && s.utilization < cfg.LowUtilization:
+		next = current - 1
+		reason = "idle"
+	}
+
+	return min(max(next, cfg.Min), cfg.Max), reason
+}
+
+func (a *Autoscaler) scale(from, to int, reason string, s autoscaleSample) {
+	if err := a.pool.Resize(to); err != nil {
+		a.logger.Warn("autoscaler failed to resize worker pool",
+			slog.Int("from", from),
+			slog.Int("to", to),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	direction := "up"
+	if to < from {
+		direction = "down"
+	}
+	a.lastScale = time.Now()
+	a.decisions.Inc(direction)
+	a.target.Set(float64(to))
+
+	a.logger.Info("autoscaler resized worker pool",
+		slog.Int("from", from),
+		slog.Int("to", to),
+		slog.String("reason", reason),
+		slog.Int("queue_depth", s.depth),
+		slog.Duration("queue_wait", s.wait),
+		slog.Float64("utilization", s.utilization),
+	)
+}
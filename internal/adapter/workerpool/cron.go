@@ -0,0 +1,86 @@
+package workerpool
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/usecase"
+)
+
+type CronScheduler struct {
+	jobs   *usecase.JobService
+	pool   *WorkerPool
+	tick   time.Duration
+	wg     *sync.WaitGroup
+	logger *slog.Logger
+}
+
+func NewCronScheduler(jobs *usecase.JobService, pool *WorkerPool, tick time.Duration, wg *sync.WaitGroup, logger *slog.Logger) *CronScheduler {
+	return &CronScheduler{
+		jobs:   jobs,
+		pool:   pool,
+		tick:   tick,
+		wg:     wg,
+		logger: logger,
+	}
+}
+
+func (cs *CronScheduler) Run(ctx context.Context) {
+	cs.wg.Add(1)
+	go func() {
+		defer cs.wg.Done()
+		cs.loop(ctx)
+	}()
+}
+
+func (cs *CronScheduler) loop(ctx context.Context) {
+	tasks, err := cs.jobs.CatchUp(time.Now())
+	cs.dispatch(ctx, tasks, err, "catch-up")
+
+	ticker := time.NewTicker(cs.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			cs.logger.Info("cron scheduler context done")
+			return
+		case now := <-ticker.C:
+			tasks, err := cs.jobs.Tick(now)
+			cs.dispatch(ctx, tasks, err, "tick")
+		}
+	}
+}
+
+func (cs *CronScheduler) dispatch(ctx context.Context, tasks []*model.Task, err error, phase string) {
+	if err != nil {
+		cs.logger.Warn("failed to materialize recurring tasks",
+			slog.String("phase", phase),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	for _, task := range tasks {
+		cs.logger.Debug("recurring task materialized",
+			slog.String("phase", phase),
+			slog.String("task_id", task.ID),
+		)
+		if err := cs.pool.Admit(ctx, task); err != nil {
+			// непринятая задача удаляется, как и отклонённая через API
+			cs.logger.Warn("recurring task rejected",
+				slog.String("phase", phase),
+				slog.String("task_id", task.ID),
+				slog.String("error", err.Error()),
+			)
+			if err := cs.pool.service.Discard(task.ID); err != nil {
+				cs.logger.Warn("failed to discard rejected recurring task",
+					slog.String("task_id", task.ID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+}
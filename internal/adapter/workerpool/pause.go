@@ -0,0 +1,95 @@
+package workerpool
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+// SetPaused применяет состояние паузы: воркеры перестают брать задачи
+// приостановленных типов (при All — все задачи), а выполняющиеся
+// дорабатывают. Приём задач не меняется. Задачи, снятые с паузы,
+// возвращаются в очередь через буфер переполнения.
+func (wp *WorkerPool) SetPaused(state model.PauseState) {
+	state.Types = slices.Clone(state.Types)
+
+	wp.pauseMu.Lock()
+	wp.paused = state
+	var released []*model.Task
+	for taskType, tasks := range wp.parked {
+		if !state.Paused(taskType) {
+			released = append(released, tasks...)
+			delete(wp.parked, taskType)
+		}
+	}
+	close(wp.pauseChanged)
+	wp.pauseChanged = make(chan struct{})
+	wp.pauseMu.Unlock()
+
+	if len(released) > 0 {
+		wp.overflowMu.Lock()
+		wp.overflow = append(wp.overflow, released...)
+		wp.overflowMu.Unlock()
+		signal(wp.overflowReady)
+	}
+
+	wp.logger.Info("worker pool pause changed",
+		slog.Bool("all", state.All),
+		slog.Any("types", state.Types),
+		slog.Int("released", len(released)),
+	)
+}
+
+func (wp *WorkerPool) Paused() model.PauseState {
+	wp.pauseMu.Lock()
+	defer wp.pauseMu.Unlock()
+
+	state := wp.paused
+	state.Types = append([]string{}, state.Types...)
+	return state
+}
+
+// awaitResume блокирует воркера, пока пул на общей паузе.
+func (wp *WorkerPool) awaitResume(ctx context.Context) error {
+	for {
+		wp.pauseMu.Lock()
+		all, changed := wp.paused.All, wp.pauseChanged
+		wp.pauseMu.Unlock()
+
+		if !all {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// park откладывает задачу, если её тип на паузе. Статус в хранилище остаётся
+// queued, поэтому отложенные задачи поднимаются и после рестарта.
+func (wp *WorkerPool) park(task *model.Task) bool {
+	wp.pauseMu.Lock()
+	defer wp.pauseMu.Unlock()
+
+	if !wp.paused.Paused(task.Type) {
+		return false
+	}
+	wp.parked[task.Type] = append(wp.parked[task.Type], task)
+	return true
+}
+
+func (wp *WorkerPool) parkedLen() int {
+	wp.pauseMu.Lock()
+	defer wp.pauseMu.Unlock()
+
+	n := 0
+	for _, tasks := range wp.parked {
+		n += len(tasks)
+	}
+	return n
+}
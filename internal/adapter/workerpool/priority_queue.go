@@ -0,0 +1,226 @@
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+var (
+	ErrQueueClosed = errors.New("queue closed")
+	ErrQueueFull   = errors.New("queue full")
+)
+
+type queueItem struct {
+	task       *model.Task
+	enqueuedAt time.Time
+	seq        uint64
+}
+
+type taskHeap struct {
+	items []*queueItem
+	aging time.Duration
+}
+
+func (h *taskHeap) Len() int { return len(h.items) }
+
+// Less: при включенном aging эффективный приоритет задачи растёт на 1 за каждый
+// aging-интервал ожидания. Так как все задачи «стареют» с одной скоростью, сравнение
+// p_i + (now-e_i)/aging > p_j + (now-e_j)/aging не зависит от now, и инвариант кучи
+// сохраняется без пересортировки.
+func (h *taskHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+
+	if h.aging > 0 {
+		ka := int64(a.task.Priority)*int64(h.aging) - a.enqueuedAt.UnixNano()
+		kb := int64(b.task.Priority)*int64(h.aging) - b.enqueuedAt.UnixNano()
+		if ka != kb {
+			return ka > kb
+		}
+	} else if a.task.Priority != b.task.Priority {
+		return a.task.Priority > b.task.Priority
+	}
+
+	return a.seq < b.seq
+}
+
+func (h *taskHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *taskHeap) Push(x any) { h.items = append(h.items, x.(*queueItem)) }
+
+func (h *taskHeap) Pop() any {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	return item
+}
+
+// PriorityQueue — ограниченная по размеру очередь задач: выше priority — раньше,
+// при равенстве — FIFO. capacity <= 0 означает очередь без ограничения.
+type PriorityQueue struct {
+	mu       sync.Mutex
+	heap     taskHeap
+	capacity int
+	seq      uint64
+	notEmpty chan struct{}
+	notFull  chan struct{}
+	done     chan struct{}
+	closed   bool
+	// waitSum и waits копят время ожидания выданных задач для автоскейлера
+	waitSum time.Duration
+	waits   int
+}
+
+func NewPriorityQueue(capacity int, aging time.Duration) *PriorityQueue {
+	return &PriorityQueue{
+		heap:     taskHeap{aging: aging},
+		capacity: capacity,
+		notEmpty: make(chan struct{}, 1),
+		notFull:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Cap возвращает ёмкость очереди; 0 — без ограничения.
+func (q *PriorityQueue) Cap() int {
+	return max(q.capacity, 0)
+}
+
+// Push ждёт свободного места в очереди, пока не отменён ctx.
+func (q *PriorityQueue) Push(ctx context.Context, task *model.Task) error {
+	return q.PushAll(ctx, []*model.Task{task})
+}
+
+// PushAll ждёт, пока в очереди освободится место сразу под все задачи, и
+// добавляет их вместе. Пачка больше ёмкости очереди не поместится никогда,
+// поэтому для неё сразу возвращается ErrQueueFull.
+func (q *PriorityQueue) PushAll(ctx context.Context, tasks []*model.Task) error {
+	if q.capacity > 0 && len(tasks) > q.capacity {
+		return ErrQueueFull
+	}
+
+	for {
+		err := q.TryPushAll(tasks)
+		if !errors.Is(err, ErrQueueFull) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.done:
+		case <-q.notFull:
+		}
+	}
+}
+
+// TryPush не блокируется: при заполненной очереди сразу возвращает ErrQueueFull.
+func (q *PriorityQueue) TryPush(task *model.Task) error {
+	return q.TryPushAll([]*model.Task{task})
+}
+
+// TryPushAll добавляет все задачи или ни одной.
+func (q *PriorityQueue) TryPushAll(tasks []*model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	if q.capacity > 0 && q.heap.Len()+len(tasks) > q.capacity {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		q.seq++
+		heap.Push(&q.heap, &queueItem{task: task, enqueuedAt: now, seq: q.seq})
+	}
+	hasSpace := q.capacity <= 0 || q.heap.Len() < q.capacity
+	q.mu.Unlock()
+
+	signal(q.notEmpty)
+	if hasSpace {
+		signal(q.notFull)
+	}
+	return nil
+}
+
+func (q *PriorityQueue) Pop(ctx context.Context) (*model.Task, error) {
+	for {
+		q.mu.Lock()
+		if q.heap.Len() > 0 {
+			item := heap.Pop(&q.heap).(*queueItem)
+			hasMore := q.heap.Len() > 0
+			q.waitSum += time.Since(item.enqueuedAt)
+			q.waits++
+			q.mu.Unlock()
+
+			signal(q.notFull)
+			if hasMore {
+				signal(q.notEmpty)
+			}
+			return item.task, nil
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.done:
+		case <-q.notEmpty:
+		}
+	}
+}
+
+func (q *PriorityQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.heap.Len()
+}
+
+func (q *PriorityQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.done)
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// takeWaits возвращает среднее время ожидания задач, выданных с прошлого
+// вызова, и их число.
+func (q *PriorityQueue) takeWaits() (time.Duration, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	sum, n := q.waitSum, q.waits
+	q.waitSum, q.waits = 0, 0
+	if n == 0 {
+		return 0, 0
+	}
+	return sum / time.Duration(n), n
+}
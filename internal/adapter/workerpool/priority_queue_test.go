@@ -0,0 +1,130 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/adapter/workerpool"
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+func popIDs(t *testing.T, q *workerpool.PriorityQueue, n int) []string {
+	t.Helper()
+
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		task, err := q.Pop(context.Background())
+		if err != nil {
+			t.Fatalf("pop failed: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	return ids
+}
+
+func TestPriorityQueue_OrderAndFIFO(t *testing.T) {
+	q := workerpool.NewPriorityQueue(10, 0)
+	ctx := context.Background()
+
+	for _, task := range []*model.Task{
+		{ID: "low1", Priority: 1},
+		{ID: "high1", Priority: 9},
+		{ID: "low2", Priority: 1},
+		{ID: "high2", Priority: 9},
+		{ID: "mid", Priority: 5},
+	} {
+		if err := q.Push(ctx, task); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+
+	got := popIDs(t, q, 5)
+	want := []string{"high1", "high2", "mid", "low1", "low2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPriorityQueue_Aging(t *testing.T) {
+	q := workerpool.NewPriorityQueue(10, 10*time.Millisecond)
+	ctx := context.Background()
+
+	_ = q.Push(ctx, &model.Task{ID: "old", Priority: 0})
+	time.Sleep(50 * time.Millisecond)
+	_ = q.Push(ctx, &model.Task{ID: "urgent", Priority: 2})
+
+	// old ждал ~5 aging-интервалов, его эффективный приоритет уже выше 2
+	if got := popIDs(t, q, 2); got[0] != "old" {
+		t.Errorf("aged task must be dispatched first, got %v", got)
+	}
+}
+
+func TestPriorityQueue_BlocksWhenFull(t *testing.T) {
+	q := workerpool.NewPriorityQueue(1, 0)
+
+	if err := q.Push(context.Background(), &model.Task{ID: "t1"}); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Push(ctx, &model.Task{ID: "t2"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected push to block until timeout, got %v", err)
+	}
+
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- q.Push(context.Background(), &model.Task{ID: "t3"})
+	}()
+
+	if got := popIDs(t, q, 1); got[0] != "t1" {
+		t.Fatalf("unexpected task %v", got)
+	}
+	if err := <-pushed; err != nil {
+		t.Fatalf("blocked push must succeed once space frees, got %v", err)
+	}
+}
+
+func TestPriorityQueue_TryPushAllIsAtomic(t *testing.T) {
+	q := workerpool.NewPriorityQueue(3, 0)
+
+	_ = q.TryPush(&model.Task{ID: "t1"})
+	batch := []*model.Task{{ID: "t2"}, {ID: "t3"}, {ID: "t4"}}
+	if err := q.TryPushAll(batch); !errors.Is(err, workerpool.ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("rejected batch must not be partially pushed, len=%d", q.Len())
+	}
+
+	if err := q.TryPushAll(batch[:2]); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if got := popIDs(t, q, 3); got[0] != "t1" || got[1] != "t2" || got[2] != "t3" {
+		t.Errorf("unexpected order %v", got)
+	}
+}
+
+func TestPriorityQueue_Close(t *testing.T) {
+	q := workerpool.NewPriorityQueue(1, 0)
+
+	popped := make(chan error, 1)
+	go func() {
+		_, err := q.Pop(context.Background())
+		popped <- err
+	}()
+
+	q.Close()
+
+	if err := <-popped; !errors.Is(err, workerpool.ErrQueueClosed) {
+		t.Errorf("pop: expected ErrQueueClosed, got %v", err)
+	}
+	if err := q.Push(context.Background(), &model.Task{ID: "t1"}); !errors.Is(err, workerpool.ErrQueueClosed) {
+		t.Errorf("push: expected ErrQueueClosed, got %v", err)
+	}
+}
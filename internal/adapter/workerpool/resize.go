@@ -0,0 +1,107 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+// DefaultMaxWorkers — верхняя граница Resize, пока не задана SetMaxWorkers.
+const DefaultMaxWorkers = 256
+
+// workerSlot — собственный сигнал остановки воркера: stop прекращает выдачу
+// ему задач, но не прерывает уже выполняющуюся.
+type workerSlot struct {
+	id   int
+	ctx  context.Context
+	stop context.CancelFunc
+}
+
+// WorkerStats — целевой размер пула и число живых воркеров. При уменьшении
+// Running больше Target, пока снятые воркеры дорабатывают текущие задачи.
+type WorkerStats struct {
+	Target  int `json:"target"`
+	Running int `json:"running"`
+	Max     int `json:"max"`
+}
+
+func (wp *WorkerPool) Workers() WorkerStats {
+	wp.lifecycle.Lock()
+	defer wp.lifecycle.Unlock()
+
+	return WorkerStats{
+		Target:  wp.workersNum,
+		Running: int(wp.running.Load()),
+		Max:     wp.maxWorkers,
+	}
+}
+
+// SetMaxWorkers задаёт верхнюю границу Resize; текущий размер пула не меняет.
+func (wp *WorkerPool) SetMaxWorkers(n int) {
+	wp.lifecycle.Lock()
+	defer wp.lifecycle.Unlock()
+
+	wp.maxWorkers = max(n, 1)
+}
+
+// Resize меняет число воркеров на ходу в пределах [1, SetMaxWorkers]. Новые
+// воркеры запускаются сразу, лишние (последние запущенные) получают сигнал
+// остановки и выходят, закончив текущую задачу; Resize их не ждёт. До Run
+// меняется только размер, с которым пул стартует.
+func (wp *WorkerPool) Resize(n int) error {
+	if n < 1 {
+		return fmt.Errorf("%w: workers must be >= 1", apperrors.ErrInvalidData)
+	}
+
+	wp.lifecycle.Lock()
+	defer wp.lifecycle.Unlock()
+
+	if n > wp.maxWorkers {
+		return fmt.Errorf("%w: workers must be <= %d", apperrors.ErrInvalidData, wp.maxWorkers)
+	}
+	if wp.draining.Load() {
+		return fmt.Errorf("%w: worker pool is shutting down", apperrors.ErrUnavailable)
+	}
+
+	prev := wp.workersNum
+	wp.workersNum = n
+	if wp.runCtx == nil {
+		return nil
+	}
+
+	for len(wp.slots) < n {
+		wp.startWorker()
+	}
+	for len(wp.slots) > n {
+		last := wp.slots[len(wp.slots)-1]
+		wp.slots = wp.slots[:len(wp.slots)-1]
+		last.stop()
+	}
+
+	wp.logger.Info("worker pool resized",
+		slog.Int("from", prev),
+		slog.Int("to", n),
+	)
+
+	return nil
+}
+
+// startWorker вызывается под wp.lifecycle после Run.
+func (wp *WorkerPool) startWorker() {
+	wp.lastID++
+	ctx, stop := context.WithCancel(wp.intakeCtx)
+	slot := &workerSlot{id: wp.lastID, ctx: ctx, stop: stop}
+	wp.slots = append(wp.slots, slot)
+
+	runCtx, intakeCtx := wp.runCtx, wp.intakeCtx
+	wp.wg.Add(1)
+	wp.workers.Add(1)
+	go func() {
+		defer wp.wg.Done()
+		defer wp.workers.Done()
+		defer stop()
+		wp.worker(runCtx, intakeCtx, slot)
+	}()
+}
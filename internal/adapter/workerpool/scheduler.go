@@ -0,0 +1,189 @@
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+type scheduledTask struct {
+	task  *model.Task
+	at    time.Time
+	index int
+}
+
+type scheduleHeap []*scheduledTask
+
+func (h scheduleHeap) Len() int           { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x any) {
+	item := x.(*scheduledTask)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *scheduleHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// scheduler хранит отложенные задачи в min-heap по времени запуска
+// и отдаёт их в fire, когда время наступило. Одна горутина с одним таймером
+// обслуживает любое число задач; индекс по ID позволяет снять или
+// перенести задачу за O(log n).
+type scheduler struct {
+	mu    sync.Mutex
+	items scheduleHeap
+	byID  map[string]*scheduledTask
+	wake  chan struct{}
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		byID: make(map[string]*scheduledTask),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// add ставит задачу на время at; повторный add той же задачи переносит её.
+func (s *scheduler) add(task *model.Task, at time.Time) {
+	s.mu.Lock()
+	if item, ok := s.byID[task.ID]; ok {
+		item.task, item.at = task, at
+		heap.Fix(&s.items, item.index)
+	} else {
+		item := &scheduledTask{task: task, at: at}
+		heap.Push(&s.items, item)
+		s.byID[task.ID] = item
+	}
+	s.mu.Unlock()
+
+	signal(s.wake)
+}
+
+// reschedule переносит уже запланированную задачу; false — такой нет.
+func (s *scheduler) reschedule(id string, at time.Time) bool {
+	s.mu.Lock()
+	item, ok := s.byID[id]
+	if ok {
+		item.at = at
+		heap.Fix(&s.items, item.index)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		signal(s.wake)
+	}
+	return ok
+}
+
+func (s *scheduler) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.byID[id]
+	if !ok {
+		return false
+	}
+	heap.Remove(&s.items, item.index)
+	delete(s.byID, id)
+	return true
+}
+
+func (s *scheduler) get(id string) (scheduledTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.byID[id]
+	if !ok {
+		return scheduledTask{}, false
+	}
+	return *item, true
+}
+
+// snapshot возвращает копию запланированных задач в порядке запуска.
+func (s *scheduler) snapshot() []scheduledTask {
+	s.mu.Lock()
+	out := make([]scheduledTask, len(s.items))
+	for i, item := range s.items {
+		out[i] = *item
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(out, func(a, b scheduledTask) int {
+		return a.at.Compare(b.at)
+	})
+	return out
+}
+
+// drain забирает все запланированные задачи; вызывается, когда run
+// уже остановлен.
+func (s *scheduler) drain() []*model.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]*model.Task, len(s.items))
+	for i, item := range s.items {
+		tasks[i] = item.task
+	}
+	s.items = nil
+	clear(s.byID)
+	return tasks
+}
+
+func (s *scheduler) run(ctx context.Context, fire func(task *model.Task)) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		var wait time.Duration = -1
+		if s.items.Len() > 0 {
+			wait = time.Until(s.items[0].at)
+			if wait <= 0 {
+				item := heap.Pop(&s.items).(*scheduledTask)
+				delete(s.byID, item.task.ID)
+				s.mu.Unlock()
+				fire(item.task)
+				continue
+			}
+		}
+		s.mu.Unlock()
+
+		var timerC <-chan time.Time
+		if wait > 0 {
+			timer.Reset(wait)
+			timerC = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timerC:
+		}
+		timer.Stop()
+	}
+}
+
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.items.Len()
+}
@@ -0,0 +1,145 @@
+package workerpool
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+// Бенчмарки сравнивают планировщик повторов на min-heap с прежней схемой
+// «горутина с time.After на каждый повтор» при 100k ожидающих повторах:
+//
+//	go test -run ^$ -bench . -benchtime 3x ./internal/adapter/workerpool/
+
+const pendingRetries = 100_000
+
+func pendingTasks(n int) []*model.Task {
+	tasks := make([]*model.Task, n)
+	for i := range tasks {
+		tasks[i] = &model.Task{ID: strconv.Itoa(i), Type: "bench"}
+	}
+	return tasks
+}
+
+func inUse() uint64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapInuse + ms.StackInuse
+}
+
+func reportPerRetry(b *testing.B, before, after uint64) {
+	b.ReportMetric(float64(after-before)/pendingRetries, "B/retry")
+}
+
+func reportLateness(b *testing.B, late []time.Duration) {
+	slices.Sort(late)
+	b.ReportMetric(float64(late[len(late)/2].Microseconds()), "p50-late-µs")
+	b.ReportMetric(float64(late[len(late)*99/100].Microseconds()), "p99-late-µs")
+}
+
+func BenchmarkRetryScheduler_Memory100k(b *testing.B) {
+	tasks := pendingTasks(pendingRetries)
+	at := time.Now().Add(time.Hour)
+
+	for b.Loop() {
+		before := inUse()
+		s := newScheduler()
+		for i, task := range tasks {
+			s.add(task, at.Add(time.Duration(i)))
+		}
+		reportPerRetry(b, before, inUse())
+		runtime.KeepAlive(s)
+	}
+}
+
+func BenchmarkGoroutinePerRetry_Memory100k(b *testing.B) {
+	for b.Loop() {
+		before := inUse()
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < pendingRetries; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				select {
+				case <-stop:
+				case <-time.After(time.Hour):
+				}
+			}()
+		}
+		reportPerRetry(b, before, inUse())
+
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// BenchmarkRetryScheduler_Reschedule100k — стоимость переноса одного повтора,
+// когда их ждёт 100k.
+func BenchmarkRetryScheduler_Reschedule100k(b *testing.B) {
+	tasks := pendingTasks(pendingRetries)
+	at := time.Now().Add(time.Hour)
+	s := newScheduler()
+	for i, task := range tasks {
+		s.add(task, at.Add(time.Duration(i)))
+	}
+
+	i := 0
+	for b.Loop() {
+		s.reschedule(tasks[i%pendingRetries].ID, at.Add(time.Duration(pendingRetries-i)))
+		i++
+	}
+}
+
+// Lateness-бенчмарки: 100k повторов, равномерно распределённых по 200ms;
+// метрика — насколько позже назначенного времени повтор был выпущен.
+func BenchmarkRetryScheduler_Lateness100k(b *testing.B) {
+	tasks := pendingTasks(pendingRetries)
+
+	for b.Loop() {
+		s := newScheduler()
+		due := make(map[string]time.Time, pendingRetries)
+		start := time.Now().Add(50 * time.Millisecond)
+		for i, task := range tasks {
+			at := start.Add(time.Duration(i) * 2 * time.Microsecond)
+			due[task.ID] = at
+			s.add(task, at)
+		}
+
+		late := make([]time.Duration, 0, pendingRetries)
+		ctx, cancel := context.WithCancel(context.Background())
+		s.run(ctx, func(task *model.Task) {
+			late = append(late, time.Since(due[task.ID]))
+			if len(late) == pendingRetries {
+				cancel()
+			}
+		})
+		reportLateness(b, late)
+	}
+}
+
+func BenchmarkGoroutinePerRetry_Lateness100k(b *testing.B) {
+	for b.Loop() {
+		late := make([]time.Duration, pendingRetries)
+		var wg sync.WaitGroup
+		start := time.Now().Add(50 * time.Millisecond)
+		for i := range late {
+			at := start.Add(time.Duration(i) * 2 * time.Microsecond)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-time.After(time.Until(at))
+				late[i] = time.Since(at)
+			}()
+		}
+		wg.Wait()
+		reportLateness(b, late)
+	}
+}
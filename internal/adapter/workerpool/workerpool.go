@@ -2,137 +2,549 @@ package workerpool
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
-	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/folivorra/task_queue/internal/model"
 	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
 )
 
 type WorkerPool struct {
-	service    *usecase.TaskService
-	workersNum int
-	taskQueue  chan *model.Task
-	retryQueue chan *model.Task
-	wg         *sync.WaitGroup
-	logger     *slog.Logger
+	service       *usecase.TaskService
+	deadLetters   *usecase.DeadLetterService
+	workersNum    int
+	maxWorkers    int
+	taskQueue     *PriorityQueue
+	retryQueue    chan *model.Task
+	scheduler     *scheduler
+	retries       *scheduler
+	admission     Admission
+	overflow      []*model.Task
+	overflowMu    sync.Mutex
+	overflowReady chan struct{}
+	// paused — текущая пауза, parked — взятые из очереди задачи
+	// приостановленных типов, pauseChanged закрывается при каждом SetPaused
+	paused       model.PauseState
+	parked       map[string][]*model.Task
+	pauseChanged chan struct{}
+	pauseMu      sync.Mutex
+	// draining выставляется в начале Drain: новые задачи не принимаются,
+	// а упавшие не уходят в retry, а возвращаются в хранилище
+	draining atomic.Bool
+	// stopIntake останавливает выдачу задач воркерам и фоновые циклы,
+	// abort отменяет контексты выполняющихся обработчиков
+	stopIntake context.CancelFunc
+	abort      context.CancelFunc
+	// runCtx и intakeCtx сохраняются в Run, чтобы Resize мог запускать
+	// воркеров на ходу; slots — запущенные воркеры в порядке запуска
+	runCtx    context.Context
+	intakeCtx context.Context
+	slots     []*workerSlot
+	lastID    int
+	running   atomic.Int64
+	busy      atomic.Int64
+	lifecycle sync.Mutex
+	// workers и loops позволяют Drain дождаться каждой группы горутин
+	workers sync.WaitGroup
+	loops   sync.WaitGroup
+	wg      *sync.WaitGroup
+	logger  *slog.Logger
 }
 
-func NewWorkerPool(service *usecase.TaskService, workersNum int, taskQueue chan *model.Task, retryQueue chan *model.Task, wg *sync.WaitGroup, logger *slog.Logger) *WorkerPool {
-	return &WorkerPool{
-		service:    service,
-		workersNum: workersNum,
-		taskQueue:  taskQueue,
-		retryQueue: retryQueue,
-		wg:         wg,
-		logger:     logger,
+func NewWorkerPool(service *usecase.TaskService, deadLetters *usecase.DeadLetterService, workersNum int, taskQueue *PriorityQueue, retryQueue chan *model.Task, admission Admission, wg *sync.WaitGroup, logger *slog.Logger) *WorkerPool {
+	wp := &WorkerPool{
+		service:       service,
+		deadLetters:   deadLetters,
+		workersNum:    workersNum,
+		maxWorkers:    max(workersNum, DefaultMaxWorkers),
+		taskQueue:     taskQueue,
+		retryQueue:    retryQueue,
+		scheduler:     newScheduler(),
+		retries:       newScheduler(),
+		admission:     admission,
+		overflowReady: make(chan struct{}, 1),
+		parked:        make(map[string][]*model.Task),
+		pauseChanged:  make(chan struct{}),
+		wg:            wg,
+		logger:        logger,
 	}
+
+	// отменённая или просроченная задача больше не ждёт повтора
+	service.OnFinished(func(task model.Task) {
+		wp.retries.remove(task.ID)
+	})
+
+	return wp
 }
 
+// Run запускает воркеров и фоновые циклы. Отмена ctx останавливает пул
+// жёстко, как Drain с нулевым grace, но без возврата задач в хранилище;
+// для штатной остановки используйте Drain.
 func (wp *WorkerPool) Run(ctx context.Context) {
+	runCtx, abort := context.WithCancel(ctx)
+	intakeCtx, stopIntake := context.WithCancel(runCtx)
+
+	wp.goLoop(func() { wp.retryCheck(intakeCtx) })
+	wp.goLoop(func() { wp.retryTimer(intakeCtx) })
+	wp.goLoop(func() { wp.scheduleCheck(intakeCtx) })
+	wp.goLoop(func() { wp.overflowCheck(intakeCtx) })
+
+	wp.lifecycle.Lock()
+	defer wp.lifecycle.Unlock()
+
+	wp.abort, wp.stopIntake = abort, stopIntake
+	wp.runCtx, wp.intakeCtx = runCtx, intakeCtx
+	for len(wp.slots) < wp.workersNum {
+		wp.startWorker()
+	}
+}
+
+func (wp *WorkerPool) goLoop(fn func()) {
 	wp.wg.Add(1)
+	wp.loops.Add(1)
 	go func() {
 		defer wp.wg.Done()
-		wp.retryCheck(ctx)
+		defer wp.loops.Done()
+		fn()
 	}()
+}
 
-	for i := 0; i < wp.workersNum; i++ {
-		wp.wg.Add(1)
-		go func() {
-			defer wp.wg.Done()
-			wp.worker(ctx, i+1, wp.taskQueue)
-		}()
+// QueueLen — число задач, ожидающих воркера, включая буфер переполнения
+// и задачи, отложенные паузой.
+func (wp *WorkerPool) QueueLen() int {
+	parked := wp.parkedLen()
+
+	wp.overflowMu.Lock()
+	defer wp.overflowMu.Unlock()
+
+	return wp.taskQueue.Len() + len(wp.overflow) + parked
+}
+
+// DispatchableLen — число задач, которые воркеры могут взять сейчас: без
+// отложенных паузой, а при общей паузе — ноль. По нему autoscaler оценивает
+// нагрузку, чтобы не наращивать пул под задачи, которые всё равно ждут.
+func (wp *WorkerPool) DispatchableLen() int {
+	wp.pauseMu.Lock()
+	all := wp.paused.All
+	wp.pauseMu.Unlock()
+	if all {
+		return 0
 	}
+
+	wp.overflowMu.Lock()
+	defer wp.overflowMu.Unlock()
+
+	return wp.taskQueue.Len() + len(wp.overflow)
+}
+
+// RetryLen — число задач, ожидающих повторной попытки.
+func (wp *WorkerPool) RetryLen() int {
+	return len(wp.retryQueue) + wp.retries.len()
 }
 
 func (wp *WorkerPool) PushToQueue(task *model.Task) {
-	wp.taskQueue <- task
+	wp.push(context.Background(), task)
 }
 
-func (wp *WorkerPool) worker(ctx context.Context, workerID int, queue <-chan *model.Task) {
+func (wp *WorkerPool) push(ctx context.Context, task *model.Task) {
+	if err := wp.taskQueue.Push(ctx, task); err != nil {
+		wp.logger.Warn("failed to push task to queue",
+			slog.String("task_id", task.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (wp *WorkerPool) Schedule(task *model.Task) {
+	wp.scheduler.add(task, *task.RunAt)
+}
+
+func (wp *WorkerPool) Submit(task *model.Task) {
+	wp.submit(context.Background(), task)
+}
+
+func (wp *WorkerPool) submit(ctx context.Context, task *model.Task) {
+	if task.Status == model.StatusScheduled && task.RunAt != nil {
+		wp.Schedule(task)
+		return
+	}
+
+	wp.push(ctx, task)
+}
+
+// Resubmit возвращает в пул задачи, поднятые TaskService.Recover, не
+// блокируясь: то, что не поместилось в очередь, в том же порядке уходит в
+// буфер переполнения. Задачи уже были приняты до рестарта, поэтому
+// OverflowLimit к ним не применяется. Так старт не ждёт разбора очереди,
+// даже если пул на паузе и воркеры её не разбирают.
+func (wp *WorkerPool) Resubmit(tasks []*model.Task) {
+	var rest []*model.Task
+	for _, task := range tasks {
+		if task.Status == model.StatusScheduled && task.RunAt != nil {
+			wp.Schedule(task)
+			continue
+		}
+		if rest == nil {
+			err := wp.taskQueue.TryPush(task)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, ErrQueueFull) {
+				wp.logger.Warn("failed to push task to queue",
+					slog.String("task_id", task.ID),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+		}
+		rest = append(rest, task)
+	}
+	if len(rest) == 0 {
+		return
+	}
+
+	wp.overflowMu.Lock()
+	wp.overflow = append(wp.overflow, rest...)
+	wp.overflowMu.Unlock()
+	signal(wp.overflowReady)
+}
+
+// worker берёт задачи, пока не остановлен slot.ctx — сигнал самого
+// воркера или общий intakeCtx; обработчики выполняются в ctx, который Drain
+// отменяет только по истечении grace.
+func (wp *WorkerPool) worker(ctx, intakeCtx context.Context, slot *workerSlot) {
+	workerID := slot.id
+	wp.running.Add(1)
+	defer wp.running.Add(-1)
+
 	for {
-		select {
-		case <-ctx.Done():
+		// Pop отдаёт готовую задачу даже из отменённого контекста
+		if slot.ctx.Err() != nil || wp.awaitResume(slot.ctx) != nil {
 			wp.logger.Info("worker context done",
 				slog.Int("worker_id", workerID),
 			)
 			return
-		case task, ok := <-queue:
-			if !ok {
-				continue
+		}
+
+		task, err := wp.taskQueue.Pop(slot.ctx)
+		if err != nil {
+			wp.logger.Info("worker context done",
+				slog.Int("worker_id", workerID),
+			)
+			return
+		}
+		if wp.park(task) {
+			continue
+		}
+
+		busy := wp.service.Metrics().BusyWorkers
+		busy.Inc()
+		wp.busy.Add(1)
+		taskCtx, cancel := attemptContext(usecase.WithWorkerID(ctx, workerID), task)
+		err = wp.service.HandleTask(taskCtx, task)
+		cancel()
+		wp.busy.Add(-1)
+		busy.Dec()
+
+		switch {
+		case errors.Is(err, apperrors.ErrCancelled):
+			wp.logger.Info("task cancelled",
+				slog.Int("worker_id", workerID),
+				slog.String("task_id", task.ID),
+			)
+		case errors.Is(err, apperrors.ErrInterrupted):
+			wp.logger.Info("task interrupted by shutdown, left queued",
+				slog.Int("worker_id", workerID),
+				slog.String("task_id", task.ID),
+			)
+		case err != nil:
+			wp.logger.Warn("failed to handle task",
+				slog.Int("worker_id", workerID),
+				slog.String("task_id", task.ID),
+				slog.String("error", err.Error()),
+			)
+
+			// решение о повторе принимается по состоянию из репозитория:
+			// указатель из очереди не отражает прошедшую попытку
+			if current, getErr := wp.service.Get(task.ID); getErr == nil {
+				task = current
 			}
 
-			if err := wp.service.HandleTask(ctx, task); err != nil {
-				wp.logger.Warn("failed to handle task",
+			switch {
+			case task.CanRetry(wp.service.Now()) && wp.draining.Load():
+				wp.handBack(task)
+			case task.CanRetry(wp.service.Now()):
+				wp.service.Metrics().Retried.Inc(task.Type)
+				select {
+				case wp.retryQueue <- task:
+				case <-intakeCtx.Done():
+					wp.handBack(task)
+				}
+			case apperrors.IsPermanent(err):
+				wp.logger.Warn("task failed permanently",
 					slog.Int("worker_id", workerID),
 					slog.String("task_id", task.ID),
-					slog.String("error", err.Error()),
 				)
-
-				if task.MaxRetries > task.Attempts {
-					wp.retryQueue <- task
-				} else {
-					wp.logger.Warn("task failed due to max retries",
-						slog.Int("worker_id", workerID),
-						slog.String("task_id", task.ID),
-					)
-				}
-			} else {
-				wp.logger.Info("task successfully done",
+				wp.bury(task)
+			default:
+				wp.logger.Warn("task failed due to max retries or deadline",
 					slog.Int("worker_id", workerID),
 					slog.String("task_id", task.ID),
 				)
+				wp.bury(task)
 			}
+		default:
+			wp.logger.Info("task successfully done",
+				slog.Int("worker_id", workerID),
+				slog.String("task_id", task.ID),
+			)
 		}
 	}
 }
 
+// retryCheck планирует упавшие задачи: пауза считается по политике
+// повторов, а ожидание ведёт retryTimer.
 func (wp *WorkerPool) retryCheck(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
 			wp.logger.Info("retry worker context done")
 			return
-		case task, ok := <-wp.retryQueue:
-			if !ok {
+		case task := <-wp.retryQueue:
+			backoff, err := wp.service.ScheduleRetry(task.ID)
+			if err != nil {
+				wp.logger.Info("retry dropped",
+					slog.String("task_id", task.ID),
+					slog.String("error", err.Error()),
+				)
 				continue
 			}
+			wp.retries.add(task, time.Now().Add(backoff))
+		}
+	}
+}
 
-			go func(task *model.Task) {
-				backoff := calculateBackoff(task.Attempts)
+// retryTimer возвращает в очередь задачи, чья пауза истекла. Push блокирует
+// цикл, пока в очереди нет места, — повторы не обгоняют новые задачи.
+func (wp *WorkerPool) retryTimer(ctx context.Context) {
+	wp.retries.run(ctx, func(task *model.Task) {
+		if task.DeadlineExceeded(wp.service.Now()) {
+			wp.expire(task)
+			return
+		}
+		if err := wp.service.MarkQueued(task.ID); err != nil {
+			wp.logger.Info("retry dropped",
+				slog.String("task_id", task.ID),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		wp.push(ctx, task)
+	})
+	wp.logger.Info("retry timer context done")
+}
 
-				select {
-				case <-ctx.Done():
-					wp.logger.Info("retry worker context done")
-					return
-				case <-time.After(backoff):
-					wp.taskQueue <- task
-				}
-			}(task)
+// PendingRetry — задача, ожидающая повторной попытки.
+type PendingRetry struct {
+	TaskID  string    `json:"task_id"`
+	Type    string    `json:"type"`
+	RetryAt time.Time `json:"retry_at"`
+}
+
+// PendingRetries возвращает ожидающие повторы в порядке запуска.
+func (wp *WorkerPool) PendingRetries() []PendingRetry {
+	items := wp.retries.snapshot()
+	out := make([]PendingRetry, len(items))
+	for i, item := range items {
+		out[i] = PendingRetry{
+			TaskID:  item.task.ID,
+			Type:    item.task.Type,
+			RetryAt: item.at,
 		}
 	}
+	return out
 }
 
-func calculateBackoff(attempts int) time.Duration {
-	baseDelay := 100 * time.Millisecond
-	maxDelay := 5 * time.Second
+// CancelRetry отменяет задачу, ожидающую повтора.
+func (wp *WorkerPool) CancelRetry(id string) (*model.Task, error) {
+	if _, ok := wp.retries.get(id); !ok {
+		return nil, fmt.Errorf("%w: no pending retry for task %s", apperrors.ErrNotFound, id)
+	}
+	// из планировщика задачу уберёт обработчик OnFinished
+	return wp.service.Cancel(id)
+}
 
-	if attempts <= 1 {
-		return baseDelay
+// RescheduleRetry переносит ожидающий повтор на время at; время в прошлом
+// запускает повтор сразу.
+func (wp *WorkerPool) RescheduleRetry(id string, at time.Time) (PendingRetry, error) {
+	item, ok := wp.retries.get(id)
+	if !ok {
+		return PendingRetry{}, fmt.Errorf("%w: no pending retry for task %s", apperrors.ErrNotFound, id)
+	}
+	if err := wp.service.RescheduleRetry(id, at); err != nil {
+		return PendingRetry{}, err
+	}
+	if !wp.retries.reschedule(id, at) {
+		return PendingRetry{}, fmt.Errorf("%w: retry of task %s already started", apperrors.ErrConflict, id)
 	}
+	return PendingRetry{TaskID: id, Type: item.task.Type, RetryAt: at}, nil
+}
 
-	delay := baseDelay * (1 << (attempts - 1))
-	if delay > maxDelay {
-		delay = maxDelay
+func (wp *WorkerPool) expire(task *model.Task) {
+	if err := wp.service.Expire(task.ID); err != nil {
+		wp.logger.Info("expired task dropped",
+			slog.String("task_id", task.ID),
+			slog.String("error", err.Error()),
+		)
+		return
 	}
 
-	jitter := rand.Int63n(int64(delay / 2))
-	return delay + time.Duration(jitter)
+	wp.logger.Warn("task deadline exceeded before retry",
+		slog.String("task_id", task.ID),
+	)
+
+	// в dead letters уходит состояние после Expire, а не копия из планировщика
+	if current, err := wp.service.Get(task.ID); err == nil {
+		task = current
+	}
+	wp.bury(task)
 }
 
-func (wp *WorkerPool) Shutdown() {
-	close(wp.taskQueue)
+func (wp *WorkerPool) bury(task *model.Task) {
+	if _, err := wp.deadLetters.Bury(task); err != nil {
+		wp.logger.Error("failed to move task to dead letters",
+			slog.String("task_id", task.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	wp.service.Metrics().Dead.Inc(task.Type)
+}
+
+// attemptContext ограничивает попытку таймаутом задачи и её абсолютным дедлайном.
+func attemptContext(ctx context.Context, task *model.Task) (context.Context, context.CancelFunc) {
+	cancels := make([]context.CancelFunc, 0, 2)
+
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, time.Duration(task.Timeout),
+			fmt.Errorf("%w: attempt exceeded timeout %s", apperrors.ErrTimedOut, time.Duration(task.Timeout)))
+		cancels = append(cancels, cancel)
+	}
+
+	if task.Deadline != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadlineCause(ctx, *task.Deadline,
+			fmt.Errorf("%w: deadline exceeded", apperrors.ErrTimedOut))
+		cancels = append(cancels, cancel)
+	}
+
+	return ctx, func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+func (wp *WorkerPool) scheduleCheck(ctx context.Context) {
+	wp.scheduler.run(ctx, func(task *model.Task) {
+		if err := wp.service.MarkQueued(task.ID); err != nil {
+			wp.logger.Info("scheduled task dropped",
+				slog.String("task_id", task.ID),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+
+		wp.push(ctx, task)
+	})
+	wp.logger.Info("scheduler context done")
+}
+
+// Drain штатно останавливает пул: перестаёт принимать задачи (Admit
+// отвечает ErrUnavailable), перестаёт выдавать их воркерам и ждёт не дольше
+// grace, пока выполняющиеся задачи завершатся. Затем оставшиеся обработчики
+// отменяются — прерванная попытка не засчитывается, задача остаётся queued.
+// Задачи, ожидавшие повтора, возвращаются в хранилище в статусе queued, а
+// задачи из очереди и отложенные уже сохранены как queued/scheduled; все они
+// поднимутся через TaskService.Recover при следующем запуске. Drain
+// закрывает очереди, только когда в них больше никто не пишет.
+func (wp *WorkerPool) Drain(grace time.Duration) {
+	if !wp.draining.CompareAndSwap(false, true) {
+		return
+	}
+
+	wp.lifecycle.Lock()
+	stopIntake, abort := wp.stopIntake, wp.abort
+	wp.lifecycle.Unlock()
+
+	if stopIntake != nil {
+		stopIntake()
+
+		finished := make(chan struct{})
+		go func() {
+			wp.workers.Wait()
+			close(finished)
+		}()
+
+		select {
+		case <-finished:
+		case <-time.After(grace):
+			wp.logger.Warn("drain grace period expired, interrupting running tasks",
+				slog.Duration("grace", grace),
+			)
+			abort()
+			<-finished
+		}
+
+		wp.loops.Wait()
+		abort()
+	}
+
+	// воркеры и retry-циклы остановлены, писать в retryQueue больше некому
+	handedBack := 0
+	for len(wp.retryQueue) > 0 {
+		wp.handBack(<-wp.retryQueue)
+		handedBack++
+	}
+	for _, task := range wp.retries.drain() {
+		wp.handBack(task)
+		handedBack++
+	}
+
+	wp.taskQueue.Close()
 	close(wp.retryQueue)
+
+	wp.logger.Info("worker pool drained",
+		slog.Int("queued_left", wp.QueueLen()),
+		slog.Int("retries_handed_back", handedBack),
+		slog.Int("scheduled_left", wp.scheduler.len()),
+		slog.Int("parked_left", wp.parkedLen()),
+	)
+}
+
+// Shutdown останавливает пул без ожидания выполняющихся задач.
+func (wp *WorkerPool) Shutdown() {
+	wp.Drain(0)
+}
+
+// handBack возвращает задачу, ожидавшую повтора, в хранилище как queued,
+// чтобы её поднял Recover.
+func (wp *WorkerPool) handBack(task *model.Task) {
+	if err := wp.service.MarkQueued(task.ID); err != nil {
+		wp.logger.Info("retry dropped",
+			slog.String("task_id", task.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	wp.logger.Info("task handed back for recovery",
+		slog.String("task_id", task.ID),
+	)
 }
@@ -2,7 +2,11 @@ package workerpool_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -11,34 +15,1041 @@ import (
 	"github.com/folivorra/task_queue/internal/model"
 	"github.com/folivorra/task_queue/internal/repository/inmemory"
 	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+	"github.com/folivorra/task_queue/pkg/metrics"
 	"log/slog"
 )
 
 func TestWorkerPool_ProcessTasks(t *testing.T) {
 	repo := inmemory.NewTaskInMemoryRepo()
 	service := usecase.NewTaskService(repo)
+	service.Register("echo", func(ctx context.Context, task *model.Task) (any, error) {
+		return task.Payload, nil
+	})
 	logger := slog.New(
 		slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
 			Level: slog.LevelDebug,
 		}),
 	)
-	taskQueue := make(chan *model.Task, 10)
+	taskQueue := workerpool.NewPriorityQueue(10, 0)
 	retryQueue := make(chan *model.Task, 10)
 
-	wp := workerpool.NewWorkerPool(service, 2, taskQueue, retryQueue, &sync.WaitGroup{}, logger)
+	wp := workerpool.NewWorkerPool(service, usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service), 2, taskQueue, retryQueue, workerpool.Admission{}, &sync.WaitGroup{}, logger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	wp.Run(ctx)
 
-	task := &model.Task{ID: "task1", MaxRetries: 3}
+	ids := []string{"task1", "task2", "task3"}
+	for _, id := range ids {
+		task := &model.Task{ID: id, Type: "echo", Payload: id, MaxRetries: 3}
+		_ = service.Save(task)
+		wp.PushToQueue(task)
+	}
+
+	waitCtx, cancelWait := context.WithTimeout(ctx, 2*time.Second)
+	defer cancelWait()
+	for _, id := range ids {
+		got, err := service.Wait(waitCtx, id)
+		if err != nil {
+			t.Fatalf("task %s did not finish: %v", id, err)
+		}
+		if got.Status != model.StatusDone || string(got.Result) != strconv.Quote(id) {
+			t.Errorf("unexpected task %s: status=%s result=%s", id, got.Status, got.Result)
+		}
+	}
+}
+
+func TestWorkerPool_ScheduledTaskWaitsUntilDue(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+	started := make(chan time.Time, 1)
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		started <- time.Now()
+		return nil, nil
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service), 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	runAt := time.Now().Add(100 * time.Millisecond)
+	task := &model.Task{ID: "task1", Type: "noop", RunAt: &runAt}
+	if err := service.Save(task); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if got, _ := service.Get("task1"); got.Status != model.StatusScheduled {
+		t.Fatalf("status = %s, want %s", got.Status, model.StatusScheduled)
+	}
+	wp.Submit(task)
+
+	waitCtx, cancelWait := context.WithTimeout(ctx, 2*time.Second)
+	defer cancelWait()
+	got, err := service.Wait(waitCtx, "task1")
+	if err != nil {
+		t.Fatalf("scheduled task did not finish: %v", err)
+	}
+	if got.Status != model.StatusDone {
+		t.Errorf("status = %s, want %s", got.Status, model.StatusDone)
+	}
+	if at := <-started; at.Before(runAt) {
+		t.Errorf("task started %s before run_at", runAt.Sub(at))
+	}
+}
+
+func TestCronScheduler_MaterializesTasks(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	jobs := usecase.NewJobService(inmemory.NewJobInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wg := &sync.WaitGroup{}
+	wp := workerpool.NewWorkerPool(service, usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service), 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, wg, logger)
+	cs := workerpool.NewCronScheduler(jobs, wp, 20*time.Millisecond, wg, logger)
+
+	if err := jobs.Create(&model.RecurringJob{ID: "j1", Schedule: "@every 100ms", Type: "noop"}); err != nil {
+		t.Fatalf("create job failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.Run(ctx)
+	cs.Run(ctx)
+
+	time.Sleep(350 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	done := 0
+	for _, task := range service.List() {
+		if task.Status == model.StatusDone {
+			done++
+		}
+	}
+	if done < 2 {
+		t.Errorf("expected at least 2 recurring runs, got %d", done)
+	}
+}
+
+func TestWorkerPool_ExhaustedTaskMovesToDeadLetters(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+	service.Register("broken", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, errors.New("downstream unavailable")
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	task := &model.Task{ID: "task1", Type: "broken", MaxRetries: 2, CallbackURL: "http://hooks.example.com/done"}
 	_ = service.Save(task)
 	wp.PushToQueue(task)
 
-	time.Sleep(600 * time.Millisecond)
+	time.Sleep(500 * time.Millisecond)
+
+	dl, err := deadLetters.Get("task1")
+	if err != nil {
+		t.Fatalf("expected dead letter, got %v", err)
+	}
+	if len(dl.Errors) != 2 || dl.Errors[0].Attempt != 1 || dl.Errors[1].Error != "downstream unavailable" {
+		t.Errorf("unexpected attempt errors: %+v", dl.Errors)
+	}
+
+	reject := func(*model.Task) error { return apperrors.ErrOverloaded }
+	if _, err := deadLetters.Requeue("task1", reject); !errors.Is(err, apperrors.ErrOverloaded) {
+		t.Fatalf("expected ErrOverloaded, got %v", err)
+	}
+	if _, err := deadLetters.Get("task1"); err != nil {
+		t.Errorf("rejected requeue must keep the dead letter, got %v", err)
+	}
+	if got, _ := service.Get("task1"); got.Attempts != 2 || got.Status != model.StatusFailed ||
+		got.FinishedAt == nil || got.LastError == "" || got.CallbackStatus != model.CallbackPending {
+		t.Errorf("rejected requeue must restore the task, got %+v", got)
+	}
+
+	var admitted []string
+	requeued, err := deadLetters.Requeue("task1", func(task *model.Task) error {
+		admitted = append(admitted, task.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("requeue failed: %v", err)
+	}
+	if requeued.Attempts != 0 || requeued.Status != model.StatusQueued {
+		t.Errorf("requeued task must be reset, got %+v", requeued)
+	}
+	if requeued.FinishedAt != nil || requeued.LastError != "" || requeued.FailedPermanently || requeued.CallbackStatus != "" {
+		t.Errorf("requeued task must drop the previous outcome, got %+v", requeued)
+	}
+	if len(requeued.History) != 2 {
+		t.Errorf("requeued task must keep its history, got %+v", requeued.History)
+	}
+	if len(admitted) != 1 {
+		t.Errorf("requeued task must pass admission once, got %v", admitted)
+	}
+	if _, err := deadLetters.Get("task1"); err == nil {
+		t.Error("requeued task must leave dead letters")
+	}
+}
+
+func TestWorkerPool_AttemptTimeout(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+	service.Register("hung", func(ctx context.Context, task *model.Task) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	task := &model.Task{ID: "task1", Type: "hung", MaxRetries: 2, Timeout: model.Duration(50 * time.Millisecond)}
+	_ = service.Save(task)
+	wp.PushToQueue(task)
+
+	time.Sleep(400 * time.Millisecond)
 
 	got, _ := service.Get("task1")
-	if got.Status != model.StatusDone && got.Status != model.StatusFailed {
-		t.Errorf("unexpected task status: %s", got.Status)
+	if got.Status != model.StatusTimedOut || got.Attempts != 2 {
+		t.Errorf("expected timed_out after 2 attempts, got %s after %d", got.Status, got.Attempts)
+	}
+
+	dl, err := deadLetters.Get("task1")
+	if err != nil {
+		t.Fatalf("expected dead letter, got %v", err)
+	}
+	if len(dl.Errors) != 2 || !strings.Contains(dl.Errors[0].Error, "timed out") {
+		t.Errorf("unexpected attempt errors: %+v", dl.Errors)
+	}
+}
+
+func TestWorkerPool_DeadlineStopsRetries(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+	service.Register("broken", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, errors.New("downstream unavailable")
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	// первый ретрай ждёт 100ms, дедлайн наступит раньше
+	deadline := time.Now().Add(50 * time.Millisecond)
+	task := &model.Task{ID: "task1", Type: "broken", MaxRetries: 10, Deadline: &deadline}
+	_ = service.Save(task)
+	wp.PushToQueue(task)
+
+	time.Sleep(400 * time.Millisecond)
+
+	got, _ := service.Get("task1")
+	if got.Status != model.StatusTimedOut || got.Attempts != 1 {
+		t.Errorf("expected timed_out after 1 attempt, got %s after %d", got.Status, got.Attempts)
+	}
+	dl, err := deadLetters.Get("task1")
+	if err != nil {
+		t.Fatalf("expected dead letter, got %v", err)
+	}
+	if dl.Task.Status != model.StatusTimedOut || dl.Task.FinishedAt == nil {
+		t.Errorf("dead letter must hold the expired task, got status=%s finished_at=%v", dl.Task.Status, dl.Task.FinishedAt)
+	}
+	if len(dl.Errors) != 2 || dl.Errors[0].Error != "downstream unavailable" ||
+		!strings.Contains(dl.Errors[1].Error, "deadline exceeded") {
+		t.Errorf("dead letter must record the attempt and the expired deadline, got %+v", dl.Errors)
+	}
+}
+
+func TestWorkerPool_AdmissionPolicies(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	newPool := func(admission workerpool.Admission) *workerpool.WorkerPool {
+		return workerpool.NewWorkerPool(service, usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service), 1, workerpool.NewPriorityQueue(1, 0), make(chan *model.Task, 10), admission, &sync.WaitGroup{}, logger)
+	}
+	save := func(id string) *model.Task {
+		task := &model.Task{ID: id, Type: "noop"}
+		_ = service.Save(task)
+		return task
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		wp := newPool(workerpool.Admission{Policy: workerpool.AdmitReject})
+		if err := wp.Admit(context.Background(), save("r1")); err != nil {
+			t.Fatalf("first task must be admitted: %v", err)
+		}
+		if err := wp.Admit(context.Background(), save("r2")); !errors.Is(err, apperrors.ErrOverloaded) {
+			t.Errorf("expected ErrOverloaded, got %v", err)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		wp := newPool(workerpool.Admission{Policy: workerpool.AdmitWait, Timeout: 20 * time.Millisecond})
+		_ = wp.Admit(context.Background(), save("w1"))
+
+		start := time.Now()
+		if err := wp.Admit(context.Background(), save("w2")); !errors.Is(err, apperrors.ErrOverloaded) {
+			t.Errorf("expected ErrOverloaded, got %v", err)
+		}
+		if waited := time.Since(start); waited < 20*time.Millisecond {
+			t.Errorf("admission returned before timeout: %s", waited)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		wp := newPool(workerpool.Admission{Policy: workerpool.AdmitOverflow, OverflowLimit: 1})
+		for _, id := range []string{"o1", "o2"} {
+			if err := wp.Admit(context.Background(), save(id)); err != nil {
+				t.Fatalf("%s must be admitted: %v", id, err)
+			}
+		}
+		if err := wp.Admit(context.Background(), save("o3")); !errors.Is(err, apperrors.ErrOverloaded) {
+			t.Errorf("expected ErrOverloaded when overflow is full, got %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wp.Run(ctx)
+
+		waitCtx, cancelWait := context.WithTimeout(ctx, time.Second)
+		defer cancelWait()
+		for _, id := range []string{"o1", "o2"} {
+			got, err := service.Wait(waitCtx, id)
+			if err != nil {
+				t.Fatalf("overflowed task %s was not dispatched: %v", id, err)
+			}
+			if got.Status != model.StatusDone {
+				t.Errorf("%s status = %s, want %s", id, got.Status, model.StatusDone)
+			}
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		wp := newPool(workerpool.Admission{Policy: workerpool.AdmitReject})
+		wp.Shutdown()
+		if err := wp.Admit(context.Background(), save("c1")); !errors.Is(err, apperrors.ErrUnavailable) {
+			t.Errorf("expected ErrUnavailable, got %v", err)
+		}
+	})
+}
+
+func TestWorkerPool_Metrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := usecase.NewMetrics(registry)
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo(), usecase.WithMetrics(m))
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	service.Register("broken", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, errors.New("boom")
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service), 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	for _, task := range []*model.Task{
+		{ID: "ok", Type: "noop"},
+		{ID: "bad", Type: "broken", MaxRetries: 2},
+	} {
+		_ = service.Save(task)
+		_ = wp.Admit(ctx, task)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for m.Dead.Value("broken") == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	checks := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"enqueued noop", m.Enqueued.Value("noop"), 1},
+		{"succeeded noop", m.Succeeded.Value("noop"), 1},
+		{"failed broken", m.Failed.Value("broken"), 2},
+		{"retried broken", m.Retried.Value("broken"), 1},
+		{"dead broken", m.Dead.Value("broken"), 1},
+		{"busy workers", m.BusyWorkers.Value(), 0},
+		{"handler observations", float64(m.HandlerDuration.Count("broken")), 2},
+		{"queue wait observations", float64(m.QueueWait.Count("noop")), 1},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if wp.QueueLen() != 0 || wp.RetryLen() != 0 {
+		t.Errorf("queues must be drained: queue=%d retry=%d", wp.QueueLen(), wp.RetryLen())
+	}
+}
+
+func TestWorkerPool_ErrorClassification(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+	service.Register("invalid", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, apperrors.Permanent(errors.New("bad payload"))
+	})
+	var throttledRuns []time.Time
+	service.Register("throttled", func(ctx context.Context, task *model.Task) (any, error) {
+		throttledRuns = append(throttledRuns, time.Now())
+		if len(throttledRuns) == 1 {
+			return nil, apperrors.RetryAfter(errors.New("rate limited"), 300*time.Millisecond)
+		}
+		return nil, nil
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	invalid := &model.Task{ID: "invalid", Type: "invalid", MaxRetries: 3}
+	throttled := &model.Task{ID: "throttled", Type: "throttled", MaxRetries: 3}
+	for _, task := range []*model.Task{invalid, throttled} {
+		_ = service.Save(task)
+		wp.PushToQueue(task)
+	}
+
+	time.Sleep(600 * time.Millisecond)
+
+	got, _ := service.Get("invalid")
+	if got.Attempts != 1 || got.Status != model.StatusFailed || !got.FailedPermanently {
+		t.Errorf("permanent error must fail without retries, got %s after %d attempts", got.Status, got.Attempts)
+	}
+	if _, err := deadLetters.Get("invalid"); err != nil {
+		t.Errorf("permanently failed task must move to dead letters, got %v", err)
+	}
+
+	got, _ = service.Get("throttled")
+	if got.Status != model.StatusDone || len(throttledRuns) != 2 {
+		t.Fatalf("expected throttled task to succeed on retry, got %s after %d runs", got.Status, len(throttledRuns))
+	}
+	if gap := throttledRuns[1].Sub(throttledRuns[0]); gap < 300*time.Millisecond {
+		t.Errorf("retry-after must override the backoff, retried after %s", gap)
+	}
+}
+
+func TestWorkerPool_Drain(t *testing.T) {
+	newPool := func(service *usecase.TaskService) *workerpool.WorkerPool {
+		deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		return workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+	}
+
+	t.Run("running task finishes within grace", func(t *testing.T) {
+		service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+		started := make(chan struct{}, 1)
+		service.Register("slow", func(ctx context.Context, task *model.Task) (any, error) {
+			started <- struct{}{}
+			time.Sleep(200 * time.Millisecond)
+			return nil, nil
+		})
+		wp := newPool(service)
+		wp.Run(context.Background())
+
+		running := &model.Task{ID: "running", Type: "slow"}
+		waiting := &model.Task{ID: "waiting", Type: "slow"}
+		for _, task := range []*model.Task{running, waiting} {
+			_ = service.Save(task)
+			if err := wp.Admit(context.Background(), task); err != nil {
+				t.Fatalf("admit failed: %v", err)
+			}
+		}
+		<-started
+
+		wp.Drain(time.Second)
+
+		if got, _ := service.Get("running"); got.Status != model.StatusDone {
+			t.Errorf("running task must finish during drain, status = %s", got.Status)
+		}
+		if got, _ := service.Get("waiting"); got.Status != model.StatusQueued || got.Attempts != 0 {
+			t.Errorf("queued task must stay queued, got %s after %d attempts", got.Status, got.Attempts)
+		}
+	})
+
+	t.Run("running task is interrupted after grace", func(t *testing.T) {
+		service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+		started := make(chan struct{}, 1)
+		service.Register("blocking", func(ctx context.Context, task *model.Task) (any, error) {
+			started <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		wp := newPool(service)
+		wp.Run(context.Background())
+
+		task := &model.Task{ID: "task1", Type: "blocking", MaxRetries: 3}
+		_ = service.Save(task)
+		wp.PushToQueue(task)
+		<-started
+
+		begin := time.Now()
+		wp.Drain(100 * time.Millisecond)
+		if elapsed := time.Since(begin); elapsed > time.Second {
+			t.Errorf("drain must not outlive grace by much, took %s", elapsed)
+		}
+
+		got, _ := service.Get("task1")
+		if got.Status != model.StatusQueued || got.Attempts != 0 {
+			t.Errorf("interrupted task must be handed back queued, got %s after %d attempts", got.Status, got.Attempts)
+		}
+	})
+
+	t.Run("retrying task is handed back", func(t *testing.T) {
+		service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+		service.Register("flaky", func(ctx context.Context, task *model.Task) (any, error) {
+			return nil, apperrors.RetryAfter(errors.New("try later"), time.Hour)
+		})
+		wp := newPool(service)
+		wp.Run(context.Background())
+
+		task := &model.Task{ID: "task1", Type: "flaky", MaxRetries: 3}
+		_ = service.Save(task)
+		wp.PushToQueue(task)
+
+		deadline := time.Now().Add(time.Second)
+		for wp.RetryLen() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if wp.RetryLen() == 0 {
+			t.Fatal("task never reached retry backoff")
+		}
+
+		wp.Drain(time.Second)
+
+		got, _ := service.Get("task1")
+		if got.Status != model.StatusQueued || got.NextRetryAt != nil || got.Attempts != 1 {
+			t.Errorf("retrying task must be handed back queued, got %s after %d attempts", got.Status, got.Attempts)
+		}
+		if wp.RetryLen() != 0 {
+			t.Errorf("retry len = %d after drain, want 0", wp.RetryLen())
+		}
+	})
+
+	t.Run("rejects new tasks and tolerates repeated calls", func(t *testing.T) {
+		service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+		service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+			return nil, nil
+		})
+		wp := newPool(service)
+		wp.Run(context.Background())
+
+		wp.Drain(time.Second)
+		wp.Drain(time.Second)
+		wp.Shutdown()
+
+		task := &model.Task{ID: "task1", Type: "noop"}
+		_ = service.Save(task)
+		if err := wp.Admit(context.Background(), task); !errors.Is(err, apperrors.ErrUnavailable) {
+			t.Errorf("expected ErrUnavailable after drain, got %v", err)
+		}
+	})
+
+	t.Run("handed back tasks are recovered", func(t *testing.T) {
+		repo := inmemory.NewTaskInMemoryRepo()
+		service := usecase.NewTaskService(repo)
+		release := make(chan struct{})
+		service.Register("gated", func(ctx context.Context, task *model.Task) (any, error) {
+			select {
+			case <-release:
+				return nil, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+		wp := newPool(service)
+		wp.Run(context.Background())
+
+		for _, id := range []string{"a", "b", "c"} {
+			task := &model.Task{ID: id, Type: "gated"}
+			_ = service.Save(task)
+			wp.PushToQueue(task)
+		}
+		time.Sleep(50 * time.Millisecond)
+		wp.Drain(50 * time.Millisecond)
+
+		close(release)
+		restarted := usecase.NewTaskService(repo)
+		restarted.Register("gated", func(ctx context.Context, task *model.Task) (any, error) {
+			return nil, nil
+		})
+		pending, err := restarted.Recover()
+		if err != nil {
+			t.Fatalf("recover failed: %v", err)
+		}
+		if len(pending) != 3 {
+			t.Fatalf("recovered %d tasks, want 3", len(pending))
+		}
+
+		wp = newPool(restarted)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wp.Run(ctx)
+		wp.Resubmit(pending)
+		time.Sleep(200 * time.Millisecond)
+
+		for _, id := range []string{"a", "b", "c"} {
+			if got, _ := restarted.Get(id); got.Status != model.StatusDone {
+				t.Errorf("task %s status = %s after recovery, want done", id, got.Status)
+			}
+		}
+	})
+}
+
+func TestWorkerPool_PendingRetries(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	service.Register("flaky", func(ctx context.Context, task *model.Task) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs[task.ID]++
+		if runs[task.ID] == 1 {
+			return nil, apperrors.RetryAfter(errors.New("downstream is down"), time.Hour)
+		}
+		return nil, nil
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 2, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		task := &model.Task{ID: id, Type: "flaky", MaxRetries: 3}
+		_ = service.Save(task)
+		wp.PushToQueue(task)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for wp.RetryLen() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	pending := wp.PendingRetries()
+	if len(pending) != 4 {
+		t.Fatalf("pending retries = %d, want 4", len(pending))
+	}
+	for _, retry := range pending {
+		if retry.Type != "flaky" || time.Until(retry.RetryAt) < 50*time.Minute {
+			t.Errorf("unexpected pending retry %+v", retry)
+		}
+	}
+
+	if task, err := wp.CancelRetry("a"); err != nil || task.ID != "a" {
+		t.Fatalf("cancel retry failed: %v", err)
+	}
+	if got, _ := service.Get("a"); got.Status != model.StatusCancelled {
+		t.Errorf("cancelled retry status = %s, want cancelled", got.Status)
+	}
+
+	// отмена через TaskService тоже снимает повтор
+	if _, err := service.Cancel("b"); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+
+	if _, err := wp.RescheduleRetry("c", time.Now()); err != nil {
+		t.Fatalf("reschedule failed: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got, _ := service.Get("c"); got.Status != model.StatusDone || got.Attempts != 2 {
+		t.Errorf("rescheduled retry must run at once, got %s after %d attempts", got.Status, got.Attempts)
+	}
+
+	pending = wp.PendingRetries()
+	if len(pending) != 1 || pending[0].TaskID != "d" {
+		t.Errorf("pending retries = %+v, want only d", pending)
+	}
+
+	if _, err := wp.CancelRetry("a"); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a task without pending retry, got %v", err)
+	}
+	if _, err := wp.RescheduleRetry("c", time.Now()); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a finished task, got %v", err)
+	}
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	service.Register("gated", func(ctx context.Context, task *model.Task) (any, error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+
+	if err := wp.Resize(0); !errors.Is(err, apperrors.ErrInvalidData) {
+		t.Errorf("expected ErrInvalidData for 0 workers, got %v", err)
+	}
+	if err := wp.Resize(workerpool.DefaultMaxWorkers + 1); !errors.Is(err, apperrors.ErrInvalidData) {
+		t.Errorf("expected ErrInvalidData above the default max, got %v", err)
+	}
+	wp.SetMaxWorkers(3)
+	if err := wp.Resize(4); !errors.Is(err, apperrors.ErrInvalidData) {
+		t.Errorf("expected ErrInvalidData above max, got %v", err)
+	}
+	if got := wp.Workers(); got.Target != 1 || got.Max != 3 {
+		t.Errorf("rejected resize must not change the pool, got %+v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	if err := wp.Resize(3); err != nil {
+		t.Fatalf("grow failed: %v", err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		task := &model.Task{ID: id, Type: "gated"}
+		_ = service.Save(task)
+		wp.PushToQueue(task)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of 3 tasks started after growing the pool", i)
+		}
+	}
+
+	if err := wp.Resize(1); err != nil {
+		t.Fatalf("shrink failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := wp.Workers(); got.Target != 1 || got.Running != 3 {
+		t.Errorf("busy workers must finish their task before stopping, got %+v", got)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for wp.Workers().Running != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := wp.Workers(); got.Running != 1 {
+		t.Errorf("running workers = %d after shrink, want 1", got.Running)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if got, _ := service.Get(id); got.Status != model.StatusDone {
+			t.Errorf("task %s status = %s, want done", id, got.Status)
+		}
+	}
+
+	// оставшийся воркер продолжает работать
+	task := &model.Task{ID: "d", Type: "gated"}
+	_ = service.Save(task)
+	wp.PushToQueue(task)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("task was not picked up after shrinking")
+	}
+
+	wp.Drain(time.Second)
+	if err := wp.Resize(2); !errors.Is(err, apperrors.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable after drain, got %v", err)
+	}
+}
+
+func TestAutoscaler_ScalesWithLoad(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	release := make(chan struct{})
+	service.Register("gated", func(ctx context.Context, task *model.Task) (any, error) {
+		<-release
+		return nil, nil
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	wg := &sync.WaitGroup{}
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(20, 0), make(chan *model.Task, 20), workerpool.Admission{}, wg, logger)
+	registry := metrics.NewRegistry()
+	autoscaler := workerpool.NewAutoscaler(wp, workerpool.AutoscaleConfig{
+		Min:            1,
+		Max:            4,
+		Interval:       20 * time.Millisecond,
+		UpCooldown:     20 * time.Millisecond,
+		DownCooldown:   100 * time.Millisecond,
+		QueuePerWorker: 1,
+	}, registry, wg, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+	autoscaler.Run(ctx)
+
+	for i := 0; i < 10; i++ {
+		task := &model.Task{ID: strconv.Itoa(i), Type: "gated"}
+		_ = service.Save(task)
+		wp.PushToQueue(task)
+	}
+
+	waitFor := func(want int) workerpool.WorkerStats {
+		deadline := time.Now().Add(2 * time.Second)
+		for wp.Workers().Target != want && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return wp.Workers()
+	}
+
+	if got := waitFor(4); got.Target != 4 {
+		t.Fatalf("autoscaler must grow the pool to max under load, got %+v", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := wp.Workers(); got.Target > 4 {
+		t.Errorf("autoscaler exceeded max, got %+v", got)
+	}
+
+	close(release)
+	if got := waitFor(1); got.Target != 1 {
+		t.Fatalf("autoscaler must shrink the idle pool to min, got %+v", got)
+	}
+
+	var out strings.Builder
+	_, _ = registry.WriteTo(&out)
+	for _, want := range []string{
+		`taskqueue_autoscaler_decisions_total{direction="up"}`,
+		`taskqueue_autoscaler_decisions_total{direction="down"}`,
+		"taskqueue_autoscaler_target_workers 1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics output has no %q", want)
+		}
+	}
+}
+
+func TestAutoscaler_IgnoresPausedBacklog(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	service.Register("email", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	wg := &sync.WaitGroup{}
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 1, workerpool.NewPriorityQueue(20, 0), make(chan *model.Task, 20), workerpool.Admission{}, wg, logger)
+	wp.SetPaused(model.PauseState{Types: []string{"email"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	for i := 0; i < 10; i++ {
+		task := &model.Task{ID: strconv.Itoa(i), Type: "email"}
+		_ = service.Save(task)
+		_ = wp.Admit(ctx, task)
+	}
+
+	// единственный воркер откладывает все задачи приостановленного типа
+	deadline := time.Now().Add(time.Second)
+	for wp.DispatchableLen() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := wp.QueueLen(); got != 10 {
+		t.Fatalf("queue len = %d, want 10 parked tasks", got)
+	}
+
+	autoscaler := workerpool.NewAutoscaler(wp, workerpool.AutoscaleConfig{
+		Min:            1,
+		Max:            4,
+		Interval:       10 * time.Millisecond,
+		UpCooldown:     10 * time.Millisecond,
+		QueuePerWorker: 1,
+	}, metrics.NewRegistry(), wg, logger)
+	autoscaler.Run(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+	if got := wp.Workers(); got.Target != 1 {
+		t.Errorf("autoscaler must not grow the pool for a paused backlog, got %+v", got)
+	}
+}
+
+func TestWorkerPool_Pause(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	var mu sync.Mutex
+	var ran []string
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	handler := func(ctx context.Context, task *model.Task) (any, error) {
+		if task.ID == "long" {
+			started <- struct{}{}
+			<-release
+		}
+		mu.Lock()
+		ran = append(ran, task.ID)
+		mu.Unlock()
+		return nil, nil
+	}
+	service.Register("email", handler)
+	service.Register("sms", handler)
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 2, workerpool.NewPriorityQueue(10, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+	pauses := usecase.NewPauseService(inmemory.NewPauseInMemoryRepo())
+	pauses.OnChange(wp.SetPaused)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	enqueue := func(id, taskType string) {
+		task := &model.Task{ID: id, Type: taskType}
+		_ = service.Save(task)
+		if err := wp.Admit(context.Background(), task); err != nil {
+			t.Fatalf("enqueue of %s must succeed while paused, got %v", id, err)
+		}
+	}
+	status := func(id string) model.TaskStatus {
+		got, _ := service.Get(id)
+		return got.Status
+	}
+
+	enqueue("long", "sms")
+	<-started
+
+	if _, err := pauses.Pause(""); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	enqueue("email1", "email")
+	enqueue("sms1", "sms")
+	close(release)
+	time.Sleep(100 * time.Millisecond)
+
+	if s := status("long"); s != model.StatusDone {
+		t.Errorf("running task must finish during pause, status = %s", s)
+	}
+	if status("email1") != model.StatusQueued || status("sms1") != model.StatusQueued {
+		t.Errorf("paused pool must not start tasks: email1=%s sms1=%s", status("email1"), status("sms1"))
+	}
+	if got := wp.DispatchableLen(); got != 0 {
+		t.Errorf("dispatchable len = %d during global pause, want 0", got)
+	}
+
+	// с общей паузы на паузу только email
+	if _, err := pauses.Resume(""); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if _, err := pauses.Pause("email"); err != nil {
+		t.Fatalf("pause email failed: %v", err)
+	}
+	enqueue("email2", "email")
+	time.Sleep(100 * time.Millisecond)
+
+	if s := status("sms1"); s != model.StatusDone {
+		t.Errorf("sms must run while only email is paused, status = %s", s)
+	}
+	if status("email1") != model.StatusQueued || status("email2") != model.StatusQueued {
+		t.Errorf("email tasks must wait: email1=%s email2=%s", status("email1"), status("email2"))
+	}
+	if got := wp.QueueLen(); got != 2 {
+		t.Errorf("queue len = %d, want 2 parked tasks", got)
+	}
+	if got := wp.DispatchableLen(); got != 0 {
+		t.Errorf("dispatchable len = %d, parked tasks must be excluded", got)
+	}
+	if state := wp.Paused(); state.All || !state.Paused("email") {
+		t.Errorf("unexpected pause state %+v", state)
+	}
+
+	if _, err := pauses.Resume("email"); err != nil {
+		t.Fatalf("resume email failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	for _, id := range []string{"email1", "email2"} {
+		if s := status(id); s != model.StatusDone {
+			t.Errorf("task %s status = %s after resume, want done", id, s)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ran) != 4 {
+		t.Errorf("tasks ran %v, want each exactly once", ran)
+	}
+}
+
+func TestWorkerPool_ResubmitWhilePaused(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), service)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	wp := workerpool.NewWorkerPool(service, deadLetters, 2, workerpool.NewPriorityQueue(2, 0), make(chan *model.Task, 10), workerpool.Admission{}, &sync.WaitGroup{}, logger)
+	// пауза, сохранённая до рестарта, выставляется до Run, как в main
+	wp.SetPaused(model.PauseState{All: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wp.Run(ctx)
+
+	var pending []*model.Task
+	for i := range 5 {
+		task := &model.Task{ID: fmt.Sprintf("task%d", i), Type: "noop"}
+		_ = service.Save(task)
+		pending = append(pending, task)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wp.Resubmit(pending)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("resubmit of a backlog larger than the queue must not block while paused")
+	}
+	for _, task := range pending {
+		if got, _ := service.Get(task.ID); got.Status != model.StatusQueued {
+			t.Errorf("task %s status = %s during pause, want queued", task.ID, got.Status)
+		}
+	}
+
+	wp.SetPaused(model.PauseState{})
+	for _, task := range pending {
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), 2*time.Second)
+		got, err := service.Wait(waitCtx, task.ID)
+		waitCancel()
+		if err != nil || got.Status != model.StatusDone {
+			t.Errorf("task %s must run after resume, got %v (%v)", task.ID, got, err)
+		}
 	}
 }
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type BatchMode string
+
+const (
+	// BatchPartial принимает каждую задачу пачки независимо.
+	BatchPartial BatchMode = "partial"
+	// BatchAtomic принимает пачку целиком или не принимает ничего.
+	BatchAtomic BatchMode = "atomic"
+)
+
+type BatchItemStatus string
+
+const (
+	BatchCreated  BatchItemStatus = "created"
+	BatchConflict BatchItemStatus = "conflict"
+	BatchInvalid  BatchItemStatus = "invalid"
+	BatchRejected BatchItemStatus = "rejected"
+	BatchFailed   BatchItemStatus = "failed"
+	// BatchSkipped — корректная задача атомарной пачки, не принятая из-за других.
+	BatchSkipped BatchItemStatus = "skipped"
+)
+
+type BatchItemResult struct {
+	Index  int             `json:"index"`
+	Status BatchItemStatus `json:"status"`
+	Task   *Task           `json:"task,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type BatchResult struct {
+	Mode    BatchMode         `json:"mode"`
+	Created int               `json:"created"`
+	Results []BatchItemResult `json:"results"`
+	// Error — ошибка чтения тела запроса; результаты до неё остаются в силе.
+	Error string `json:"error,omitempty"`
+}
+
+func ParseBatchMode(s string) (BatchMode, error) {
+	switch m := BatchMode(s); m {
+	case "":
+		return BatchPartial, nil
+	case BatchPartial, BatchAtomic:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: unknown batch mode %q", apperrors.ErrInvalidData, s)
+	}
+}
@@ -1,7 +1,73 @@
 package model
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
 type CreateTaskRequest struct {
-	ID         string `json:"id"`
-	Payload    string `json:"payload"`
-	MaxRetries int    `json:"max_retries"`
+	ID          string       `json:"id"`
+	Type        string       `json:"type"`
+	Payload     string       `json:"payload"`
+	Priority    int          `json:"priority"`
+	MaxRetries  int          `json:"max_retries"`
+	RunAt       *time.Time   `json:"run_at,omitempty"`
+	Delay       Duration     `json:"delay,omitempty"`
+	Timeout     Duration     `json:"timeout,omitempty"`
+	Deadline    *time.Time   `json:"deadline,omitempty"`
+	CallbackURL string       `json:"callback_url,omitempty"`
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+}
+
+func (r CreateTaskRequest) ScheduledAt(now time.Time) (*time.Time, error) {
+	if r.Delay < 0 {
+		return nil, fmt.Errorf("%w: delay must be >= 0", apperrors.ErrInvalidData)
+	}
+	if r.RunAt != nil && r.Delay != 0 {
+		return nil, fmt.Errorf("%w: run_at and delay are mutually exclusive", apperrors.ErrInvalidData)
+	}
+
+	switch {
+	case r.RunAt != nil:
+		return r.RunAt, nil
+	case r.Delay > 0:
+		runAt := now.Add(time.Duration(r.Delay))
+		return &runAt, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (r CreateTaskRequest) NewTask(now time.Time) (*Task, error) {
+	runAt, err := r.ScheduledAt(now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Task{
+		ID:          r.ID,
+		Type:        r.Type,
+		Payload:     r.Payload,
+		Priority:    r.Priority,
+		MaxRetries:  r.MaxRetries,
+		RunAt:       runAt,
+		Timeout:     r.Timeout,
+		Deadline:    r.Deadline,
+		CallbackURL: r.CallbackURL,
+		RetryPolicy: r.RetryPolicy,
+	}, nil
+}
+
+// Fingerprint — хэш содержимого запроса, по которому повтор с тем же
+// Idempotency-Key отличается от запроса с другим телом. Считается по
+// декодированной структуре, поэтому не зависит от порядка полей и пробелов.
+func (r CreateTaskRequest) Fingerprint() string {
+	raw, _ := json.Marshal(r)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+type AttemptError struct {
+	Attempt int       `json:"attempt"`
+	Error   string    `json:"error"`
+	At      time.Time `json:"at"`
+}
+
+type DeadLetter struct {
+	Task   Task           `json:"task"`
+	Errors []AttemptError `json:"errors"`
+	DeadAt time.Time      `json:"dead_at"`
+}
+
+// RequeueFailure — dead letter, который не удалось вернуть в очередь; он
+// остаётся в dead letters.
+type RequeueFailure struct {
+	TaskID string `json:"task_id"`
+	Error  string `json:"error"`
+}
+
+// RequeueResult — итог возврата dead letters: что ушло в очередь и что
+// осталось с причиной отказа.
+type RequeueResult struct {
+	Requeued []*Task          `json:"requeued"`
+	Failed   []RequeueFailure `json:"failed"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// StatusRetrying не хранится в задаче: это событие о том, что упавшая задача
+// ждёт backoff и вернётся в очередь в next_retry_at.
+var StatusRetrying TaskStatus = "retrying"
+
+// StatusRejected — событие о том, что сохранённая задача не прошла контроль
+// приёма и удалена; после него событий по задаче не будет.
+var StatusRejected TaskStatus = "rejected"
+
+// TaskEvent — переход задачи в новый статус. Seq возрастает монотонно в
+// пределах процесса и служит id события в SSE.
+type TaskEvent struct {
+	Seq         uint64     `json:"seq"`
+	TaskID      string     `json:"task_id"`
+	Type        string     `json:"type"`
+	Status      TaskStatus `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	Time        time.Time  `json:"time"`
+}
+
+// EventFilter отбирает события; пустые поля не ограничивают выборку.
+type EventFilter struct {
+	TaskIDs  []string
+	Types    []string
+	Statuses []TaskStatus
+}
+
+func (f EventFilter) Match(ev TaskEvent) bool {
+	if len(f.TaskIDs) > 0 && !slices.Contains(f.TaskIDs, ev.TaskID) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, ev.Type) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, ev.Status) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotencyRecord — сохранённый ответ на запрос с заголовком Idempotency-Key.
+type IdempotencyRecord struct {
+	Key         string          `json:"key"`
+	Fingerprint string          `json:"fingerprint"`
+	StatusCode  int             `json:"status_code"`
+	Body        json.RawMessage `json:"body"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+}
+
+func (r *IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,18 @@
+package model
+
+import "slices"
+
+// PauseState — какие задачи воркеры сейчас не берут: все (All) или задачи
+// перечисленных типов.
+type PauseState struct {
+	All   bool     `json:"all"`
+	Types []string `json:"types"`
+}
+
+func (s PauseState) Paused(taskType string) bool {
+	return s.All || slices.Contains(s.Types, taskType)
+}
+
+func (s PauseState) Active() bool {
+	return s.All || len(s.Types) > 0
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/folivorra/task_queue/pkg/apperrors"
+	"github.com/folivorra/task_queue/pkg/cron"
+)
+
+type CatchUpPolicy string
+
+var (
+	CatchUpSkip CatchUpPolicy = "skip"
+	CatchUpOnce CatchUpPolicy = "once"
+	CatchUpAll  CatchUpPolicy = "all"
+)
+
+type RecurringJob struct {
+	ID         string        `json:"id"`
+	Schedule   string        `json:"schedule"`
+	Type       string        `json:"type"`
+	Payload    string        `json:"payload"`
+	MaxRetries int           `json:"max_retries"`
+	CatchUp    CatchUpPolicy `json:"catch_up"`
+	Paused     bool          `json:"paused"`
+	CreatedAt  time.Time     `json:"created_at"`
+	LastRunAt  *time.Time    `json:"last_run_at,omitempty"`
+	NextRunAt  *time.Time    `json:"next_run_at,omitempty"`
+}
+
+type CreateJobRequest struct {
+	ID         string        `json:"id"`
+	Schedule   string        `json:"schedule"`
+	Type       string        `json:"type"`
+	Payload    string        `json:"payload"`
+	MaxRetries int           `json:"max_retries"`
+	CatchUp    CatchUpPolicy `json:"catch_up"`
+}
+
+func ValidateJob(j RecurringJob) error {
+	if j.ID == "" {
+		return fmt.Errorf("%w: id is required", apperrors.ErrInvalidData)
+	}
+	if j.Type == "" {
+		return fmt.Errorf("%w: type is required", apperrors.ErrInvalidData)
+	}
+	if j.MaxRetries < 0 {
+		return fmt.Errorf("%w: max_retries must be >= 0", apperrors.ErrInvalidData)
+	}
+
+	switch j.CatchUp {
+	case CatchUpSkip, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("%w: catch_up must be one of skip, once, all", apperrors.ErrInvalidData)
+	}
+
+	if _, err := cron.Parse(j.Schedule); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidData, err)
+	}
+
+	return nil
+}
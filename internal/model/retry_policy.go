@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type RetryStrategy string
+
+const (
+	// RetryFixed — всегда Base.
+	RetryFixed RetryStrategy = "fixed"
+	// RetryLinear — Base * (1 + Multiplier*(n-1)), по умолчанию Multiplier = 1.
+	RetryLinear RetryStrategy = "linear"
+	// RetryExponential — Base * Multiplier^(n-1), по умолчанию Multiplier = 2.
+	RetryExponential RetryStrategy = "exponential"
+	// RetryDecorrelated — случайная пауза от Base до предыдущей паузы,
+	// умноженной на Multiplier (по умолчанию 3), см. «decorrelated jitter».
+	RetryDecorrelated RetryStrategy = "decorrelated_jitter"
+)
+
+const (
+	defaultRetryBase = 100 * time.Millisecond
+	defaultRetryMax  = 5 * time.Second
+)
+
+// RetryPolicy задаёт паузу перед повторной попыткой задачи. Нулевые поля
+// получают значения по умолчанию: Base — 100ms, Max — 5s, Multiplier —
+// свой для каждой стратегии. Jitter — доля паузы (0..1), которая случайно
+// добавляется сверх рассчитанного значения; итог всё равно не больше Max.
+type RetryPolicy struct {
+	Strategy   RetryStrategy `json:"strategy"`
+	Base       Duration      `json:"base,omitempty"`
+	Max        Duration      `json:"max,omitempty"`
+	Multiplier float64       `json:"multiplier,omitempty"`
+	Jitter     float64       `json:"jitter,omitempty"`
+}
+
+// DefaultRetryPolicy — политика сервера, если не задана другая.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Strategy:   RetryExponential,
+		Base:       Duration(defaultRetryBase),
+		Max:        Duration(defaultRetryMax),
+		Multiplier: 2,
+		Jitter:     0.5,
+	}
+}
+
+func (p RetryPolicy) Validate() error {
+	switch p.Strategy {
+	case RetryFixed, RetryLinear, RetryExponential, RetryDecorrelated:
+	default:
+		return fmt.Errorf("%w: unknown retry strategy %q", apperrors.ErrInvalidData, p.Strategy)
+	}
+
+	if p.Base < 0 || p.Max < 0 {
+		return fmt.Errorf("%w: retry base and max must be >= 0", apperrors.ErrInvalidData)
+	}
+
+	p = p.withDefaults()
+	if p.Max < p.Base {
+		return fmt.Errorf("%w: retry max must be >= base", apperrors.ErrInvalidData)
+	}
+
+	switch {
+	case p.Multiplier < 0:
+		return fmt.Errorf("%w: retry multiplier must be >= 0", apperrors.ErrInvalidData)
+	case p.Strategy == RetryExponential && p.Multiplier < 1,
+		p.Strategy == RetryDecorrelated && p.Multiplier < 1:
+		return fmt.Errorf("%w: retry multiplier must be >= 1 for %s", apperrors.ErrInvalidData, p.Strategy)
+	}
+
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("%w: retry jitter must be between 0 and 1", apperrors.ErrInvalidData)
+	}
+
+	return nil
+}
+
+// Delay возвращает паузу после неудачной попытки attempt (с 1); prev —
+// предыдущая пауза, её использует decorrelated_jitter. rnd(n) возвращает
+// случайное число из [0, n).
+func (p RetryPolicy) Delay(attempt int, prev time.Duration, rnd func(n int64) int64) time.Duration {
+	p = p.withDefaults()
+	base, limit := float64(p.Base), float64(p.Max)
+	attempt = max(attempt, 1)
+
+	var delay float64
+	switch p.Strategy {
+	case RetryFixed:
+		delay = base
+	case RetryLinear:
+		delay = base * (1 + p.Multiplier*float64(attempt-1))
+	case RetryDecorrelated:
+		upper := math.Min(math.Max(float64(prev), base)*p.Multiplier, limit)
+		delay = base
+		if span := int64(upper - base); span > 0 {
+			delay += float64(rnd(span + 1))
+		}
+	default:
+		delay = base * math.Pow(p.Multiplier, float64(attempt-1))
+	}
+	delay = math.Min(delay, limit)
+
+	if spread := int64(delay * p.Jitter); spread > 0 {
+		delay += float64(rnd(spread))
+	}
+
+	// случайная добавка тоже не выводит паузу за Max
+	return time.Duration(math.Min(delay, limit))
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Base == 0 {
+		p.Base = Duration(defaultRetryBase)
+	}
+	if p.Max == 0 {
+		p.Max = Duration(max(defaultRetryMax, time.Duration(p.Base)))
+	}
+	if p.Multiplier == 0 {
+		switch p.Strategy {
+		case RetryLinear:
+			p.Multiplier = 1
+		case RetryDecorrelated:
+			p.Multiplier = 3
+		case RetryExponential:
+			p.Multiplier = 2
+		}
+	}
+	return p
+}
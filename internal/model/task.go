@@ -1,34 +1,129 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/folivorra/task_queue/pkg/apperrors"
 )
 
 type TaskStatus string
 
+const (
+	MinPriority = 0
+	MaxPriority = 100
+)
+
 var (
-	StatusQueued  TaskStatus = "queued"
-	StatusRunning TaskStatus = "running"
-	StatusDone    TaskStatus = "done"
-	StatusFailed  TaskStatus = "failed"
+	StatusScheduled TaskStatus = "scheduled"
+	StatusQueued    TaskStatus = "queued"
+	StatusRunning   TaskStatus = "running"
+	StatusDone      TaskStatus = "done"
+	StatusFailed    TaskStatus = "failed"
+	StatusCancelled TaskStatus = "cancelled"
+	StatusTimedOut  TaskStatus = "timed_out"
 )
 
+type Attempt struct {
+	Number     int        `json:"number"`
+	WorkerID   int        `json:"worker_id"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
 type Task struct {
-	ID         string     `json:"id"`
-	Payload    string     `json:"payload"`
-	MaxRetries int        `json:"max_retries"`
-	Attempts   int        `json:"attempts"`
-	Status     TaskStatus `json:"status"`
+	ID                string          `json:"id"`
+	Type              string          `json:"type"`
+	Payload           string          `json:"payload"`
+	Priority          int             `json:"priority"`
+	MaxRetries        int             `json:"max_retries"`
+	Attempts          int             `json:"attempts"`
+	Status            TaskStatus      `json:"status"`
+	RunAt             *time.Time      `json:"run_at,omitempty"`
+	Timeout           Duration        `json:"timeout,omitempty"`
+	Deadline          *time.Time      `json:"deadline,omitempty"`
+	Result            json.RawMessage `json:"result,omitempty"`
+	LastError         string          `json:"last_error,omitempty"`
+	History           []Attempt       `json:"history,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	QueuedAt          *time.Time      `json:"queued_at,omitempty"`
+	StartedAt         *time.Time      `json:"started_at,omitempty"`
+	FinishedAt        *time.Time      `json:"finished_at,omitempty"`
+	NextRetryAt       *time.Time      `json:"next_retry_at,omitempty"`
+	RetryPolicy       *RetryPolicy    `json:"retry_policy,omitempty"`
+	RetryDelay        Duration        `json:"retry_delay,omitempty"`
+	FailedPermanently bool            `json:"failed_permanently,omitempty"`
+	CallbackURL       string          `json:"callback_url,omitempty"`
+	CallbackStatus    CallbackStatus  `json:"callback_status,omitempty"`
+	Deliveries        []Delivery      `json:"deliveries,omitempty"`
+}
+
+// Clone возвращает независимую копию задачи. Слайсы и Result копируются;
+// поля-указатели на время при изменениях заменяются, а не правятся по месту,
+// поэтому их можно разделять.
+func (t *Task) Clone() *Task {
+	c := *t
+	c.Result = slices.Clone(t.Result)
+	c.History = slices.Clone(t.History)
+	c.Deliveries = slices.Clone(t.Deliveries)
+	if t.RetryPolicy != nil {
+		policy := *t.RetryPolicy
+		c.RetryPolicy = &policy
+	}
+	return &c
+}
+
+func (t *Task) DeadlineExceeded(now time.Time) bool {
+	return t.Deadline != nil && !now.Before(*t.Deadline)
+}
+
+// CanRetry сообщает, остались ли у задачи попытки. FailedPermanently
+// выставляется, если обработчик вернул apperrors.Permanent.
+func (t *Task) CanRetry(now time.Time) bool {
+	return !t.FailedPermanently && t.MaxRetries > t.Attempts && !t.DeadlineExceeded(now)
+}
+
+// Finished сообщает, что задача больше не будет выполняться: она успешно
+// завершена, отменена или упала без права на повтор.
+func (t *Task) Finished(now time.Time) bool {
+	switch t.Status {
+	case StatusDone, StatusCancelled:
+		return true
+	case StatusFailed, StatusTimedOut:
+		return !t.CanRetry(now)
+	default:
+		return false
+	}
 }
 
 func ValidateTask(t Task) error {
 	if t.ID == "" {
 		return fmt.Errorf("%w: id is required", apperrors.ErrInvalidData)
 	}
+	if t.Type == "" {
+		return fmt.Errorf("%w: type is required", apperrors.ErrInvalidData)
+	}
 	if t.MaxRetries < 0 {
 		return fmt.Errorf("%w: max_retries must be >= 0", apperrors.ErrInvalidData)
 	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("%w: timeout must be >= 0", apperrors.ErrInvalidData)
+	}
+	if t.Priority < MinPriority || t.Priority > MaxPriority {
+		return fmt.Errorf("%w: priority must be between %d and %d", apperrors.ErrInvalidData, MinPriority, MaxPriority)
+	}
+	if t.CallbackURL != "" {
+		if err := ValidateCallbackURL(t.CallbackURL); err != nil {
+			return err
+		}
+	}
+	if t.RetryPolicy != nil {
+		if err := t.RetryPolicy.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type TaskSort string
+
+const (
+	SortCreatedAsc  TaskSort = "created_at"
+	SortCreatedDesc TaskSort = "-created_at"
+	SortPriority    TaskSort = "priority"
+	SortID          TaskSort = "id"
+)
+
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// TaskQuery описывает выборку задач. Пустые поля не ограничивают выборку,
+// границы *After включительные, *Before — исключающие.
+type TaskQuery struct {
+	Statuses       []TaskStatus
+	Types          []string
+	IDPrefix       string
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	FinishedAfter  *time.Time
+	FinishedBefore *time.Time
+	Sort           TaskSort
+	Limit          int
+	Cursor         string
+}
+
+type TaskPage struct {
+	Tasks      []*Task `json:"tasks"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// taskCursor — ключ сортировки последней выданной задачи. Клиенту он
+// отдаётся непрозрачной base64-строкой.
+type taskCursor struct {
+	Sort      TaskSort  `json:"s"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"c"`
+	Priority  int       `json:"p"`
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры выборки.
+func (q TaskQuery) Normalize() (TaskQuery, error) {
+	switch q.Sort {
+	case "":
+		q.Sort = SortCreatedAsc
+	case SortCreatedAsc, SortCreatedDesc, SortPriority, SortID:
+	default:
+		return q, fmt.Errorf("%w: unsupported sort %q", apperrors.ErrInvalidData, q.Sort)
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultQueryLimit
+	case q.Limit < 0 || q.Limit > MaxQueryLimit:
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidData, MaxQueryLimit)
+	}
+
+	if q.Cursor != "" {
+		if _, err := q.decodeCursor(); err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
+
+func (q TaskQuery) Match(t *Task) bool {
+	if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, t.Status) {
+		return false
+	}
+	if len(q.Types) > 0 && !slices.Contains(q.Types, t.Type) {
+		return false
+	}
+	if !strings.HasPrefix(t.ID, q.IDPrefix) {
+		return false
+	}
+	if !inRange(&t.CreatedAt, q.CreatedAfter, q.CreatedBefore) {
+		return false
+	}
+	if (q.FinishedAfter != nil || q.FinishedBefore != nil) && !inRange(t.FinishedAt, q.FinishedAfter, q.FinishedBefore) {
+		return false
+	}
+	return true
+}
+
+// Less задаёт порядок выдачи. ID замыкает любой порядок, поэтому он строгий
+// и пагинация по курсору не теряет и не повторяет задачи.
+func (q TaskQuery) Less(a, b *Task) bool {
+	switch q.Sort {
+	case SortCreatedDesc:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+	case SortPriority:
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	case SortID:
+	default:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+	return a.ID < b.ID
+}
+
+// Page сортирует отфильтрованные задачи и вырезает страницу после курсора.
+// Запрос должен быть нормализован.
+func (q TaskQuery) Page(tasks []*Task) TaskPage {
+	sort.Slice(tasks, func(i, j int) bool {
+		return q.Less(tasks[i], tasks[j])
+	})
+
+	if q.Cursor != "" {
+		c, _ := q.decodeCursor()
+		last := &Task{ID: c.ID, CreatedAt: c.CreatedAt, Priority: c.Priority}
+		start := sort.Search(len(tasks), func(i int) bool {
+			return q.Less(last, tasks[i])
+		})
+		tasks = tasks[start:]
+	}
+
+	page := TaskPage{Tasks: tasks}
+	if len(tasks) > q.Limit {
+		page.Tasks = tasks[:q.Limit]
+		page.NextCursor = q.encodeCursor(page.Tasks[q.Limit-1])
+	}
+
+	return page
+}
+
+func (q TaskQuery) encodeCursor(t *Task) string {
+	raw, _ := json.Marshal(taskCursor{Sort: q.Sort, ID: t.ID, CreatedAt: t.CreatedAt, Priority: t.Priority})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func (q TaskQuery) decodeCursor() (taskCursor, error) {
+	var c taskCursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil || json.Unmarshal(raw, &c) != nil {
+		return c, fmt.Errorf("%w: malformed cursor", apperrors.ErrInvalidData)
+	}
+	if c.Sort != q.Sort {
+		return c, fmt.Errorf("%w: cursor was issued for sort %q", apperrors.ErrInvalidData, c.Sort)
+	}
+
+	return c, nil
+}
+
+func inRange(t *time.Time, after, before *time.Time) bool {
+	if t == nil {
+		return false
+	}
+	if after != nil && t.Before(*after) {
+		return false
+	}
+	if before != nil && !t.Before(*before) {
+		return false
+	}
+	return true
+}
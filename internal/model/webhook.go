@@ -0,0 +1,37 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type CallbackStatus string
+
+const (
+	// CallbackPending — задача завершилась, webhook ещё доставляется.
+	CallbackPending CallbackStatus = "pending"
+	// CallbackDelivered — получатель ответил 2xx.
+	CallbackDelivered CallbackStatus = "delivered"
+	// CallbackFailed — все попытки доставки исчерпаны.
+	CallbackFailed CallbackStatus = "failed"
+)
+
+// Delivery — попытка доставить webhook: код ответа получателя или ошибка
+// соединения.
+type Delivery struct {
+	Number     int       `json:"number"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func ValidateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: callback_url must be an absolute http(s) URL", apperrors.ErrInvalidData)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package filestore
+
+import (
+	"context"
+	"time"
+)
+
+type Compactor interface {
+	Compact() error
+}
+
+func RunCompaction(ctx context.Context, interval time.Duration, onError func(error), compactors ...Compactor) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, c := range compactors {
+				if err := c.Compact(); err != nil {
+					onError(err)
+				}
+			}
+		}
+	}
+}
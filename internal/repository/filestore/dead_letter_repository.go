@@ -0,0 +1,143 @@
+package filestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type deadLetterRecord struct {
+	Op         string            `json:"op"`
+	ID         string            `json:"id,omitempty"`
+	DeadLetter *model.DeadLetter `json:"dead_letter,omitempty"`
+}
+
+type DeadLetterFileRepo struct {
+	storage map[string]model.DeadLetter
+	journal *journal
+	sync.RWMutex
+}
+
+func NewDeadLetterFileRepo(dir string) (*DeadLetterFileRepo, error) {
+	dr := &DeadLetterFileRepo{
+		storage: make(map[string]model.DeadLetter),
+	}
+
+	j, err := openJournal(dir, "deadletters", dr.restore, dr.replay)
+	if err != nil {
+		return nil, err
+	}
+	dr.journal = j
+
+	return dr, nil
+}
+
+func (dr *DeadLetterFileRepo) restore(data json.RawMessage) error {
+	var dls []model.DeadLetter
+	if err := json.Unmarshal(data, &dls); err != nil {
+		return err
+	}
+
+	for _, dl := range dls {
+		dr.storage[dl.Task.ID] = dl
+	}
+
+	return nil
+}
+
+func (dr *DeadLetterFileRepo) replay(data json.RawMessage) error {
+	var rec deadLetterRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+
+	switch rec.Op {
+	case opPut:
+		dr.storage[rec.DeadLetter.Task.ID] = *rec.DeadLetter
+	case opDelete:
+		delete(dr.storage, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+
+	return nil
+}
+
+func (dr *DeadLetterFileRepo) Save(dl *model.DeadLetter) error {
+	dr.Lock()
+	defer dr.Unlock()
+
+	if err := dr.journal.append(deadLetterRecord{Op: opPut, DeadLetter: dl}); err != nil {
+		return err
+	}
+
+	dr.storage[dl.Task.ID] = *dl
+
+	return nil
+}
+
+func (dr *DeadLetterFileRepo) Get(taskID string) (*model.DeadLetter, error) {
+	dr.RLock()
+	defer dr.RUnlock()
+	dl, ok := dr.storage[taskID]
+	if !ok {
+		return nil, fmt.Errorf("%w: dead letter not found", apperrors.ErrNotFound)
+	}
+
+	return &dl, nil
+}
+
+func (dr *DeadLetterFileRepo) Delete(taskID string) error {
+	dr.Lock()
+	defer dr.Unlock()
+	if _, ok := dr.storage[taskID]; !ok {
+		return fmt.Errorf("%w: dead letter not found", apperrors.ErrNotFound)
+	}
+
+	if err := dr.journal.append(deadLetterRecord{Op: opDelete, ID: taskID}); err != nil {
+		return err
+	}
+
+	delete(dr.storage, taskID)
+
+	return nil
+}
+
+func (dr *DeadLetterFileRepo) List() []*model.DeadLetter {
+	dr.RLock()
+	defer dr.RUnlock()
+
+	dls := make([]*model.DeadLetter, 0, len(dr.storage))
+	for _, d := range dr.storage {
+		dl := d
+		dls = append(dls, &dl)
+	}
+
+	return dls
+}
+
+func (dr *DeadLetterFileRepo) Compact() error {
+	dr.Lock()
+	defer dr.Unlock()
+
+	dls := make([]model.DeadLetter, 0, len(dr.storage))
+	for _, dl := range dr.storage {
+		dls = append(dls, dl)
+	}
+
+	return dr.journal.compact(dls)
+}
+
+func (dr *DeadLetterFileRepo) Close() error {
+	if err := dr.Compact(); err != nil {
+		return err
+	}
+
+	dr.Lock()
+	defer dr.Unlock()
+
+	return dr.journal.close()
+}
@@ -0,0 +1,38 @@
+package filestore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/filestore"
+)
+
+func TestDeadLetterFileRepo_ReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewDeadLetterFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	_ = repo.Save(&model.DeadLetter{
+		Task:   model.Task{ID: "t1", Type: "email", Attempts: 2},
+		Errors: []model.AttemptError{{Attempt: 1, Error: "boom", At: time.Now()}},
+		DeadAt: time.Now(),
+	})
+	_ = repo.Save(&model.DeadLetter{Task: model.Task{ID: "t2"}})
+	if err := repo.Delete("t2"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	reopened, err := filestore.NewDeadLetterFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	dls := reopened.List()
+	if len(dls) != 1 || dls[0].Task.ID != "t1" || len(dls[0].Errors) != 1 || dls[0].Errors[0].Error != "boom" {
+		t.Errorf("unexpected recovered dead letters: %+v", dls)
+	}
+}
@@ -0,0 +1,143 @@
+package filestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type idempotencyRecord struct {
+	Op     string                   `json:"op"`
+	Key    string                   `json:"key,omitempty"`
+	Record *model.IdempotencyRecord `json:"record,omitempty"`
+}
+
+type IdempotencyFileRepo struct {
+	storage map[string]model.IdempotencyRecord
+	journal *journal
+	sync.RWMutex
+}
+
+func NewIdempotencyFileRepo(dir string) (*IdempotencyFileRepo, error) {
+	ir := &IdempotencyFileRepo{
+		storage: make(map[string]model.IdempotencyRecord),
+	}
+
+	j, err := openJournal(dir, "idempotency", ir.restore, ir.replay)
+	if err != nil {
+		return nil, err
+	}
+	ir.journal = j
+
+	return ir, nil
+}
+
+func (ir *IdempotencyFileRepo) restore(data json.RawMessage) error {
+	var recs []model.IdempotencyRecord
+	if err := json.Unmarshal(data, &recs); err != nil {
+		return err
+	}
+
+	for _, r := range recs {
+		ir.storage[r.Key] = r
+	}
+
+	return nil
+}
+
+func (ir *IdempotencyFileRepo) replay(data json.RawMessage) error {
+	var rec idempotencyRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+
+	switch rec.Op {
+	case opPut:
+		ir.storage[rec.Record.Key] = *rec.Record
+	case opDelete:
+		delete(ir.storage, rec.Key)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+
+	return nil
+}
+
+func (ir *IdempotencyFileRepo) Put(rec *model.IdempotencyRecord) error {
+	ir.Lock()
+	defer ir.Unlock()
+
+	if err := ir.journal.append(idempotencyRecord{Op: opPut, Record: rec}); err != nil {
+		return err
+	}
+
+	ir.storage[rec.Key] = *rec
+
+	return nil
+}
+
+func (ir *IdempotencyFileRepo) Get(key string) (*model.IdempotencyRecord, error) {
+	ir.RLock()
+	defer ir.RUnlock()
+	rec, ok := ir.storage[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: idempotency key not found", apperrors.ErrNotFound)
+	}
+
+	return &rec, nil
+}
+
+func (ir *IdempotencyFileRepo) Delete(key string) error {
+	ir.Lock()
+	defer ir.Unlock()
+	if _, ok := ir.storage[key]; !ok {
+		return fmt.Errorf("%w: idempotency key not found", apperrors.ErrNotFound)
+	}
+
+	if err := ir.journal.append(idempotencyRecord{Op: opDelete, Key: key}); err != nil {
+		return err
+	}
+
+	delete(ir.storage, key)
+
+	return nil
+}
+
+func (ir *IdempotencyFileRepo) List() []*model.IdempotencyRecord {
+	ir.RLock()
+	defer ir.RUnlock()
+
+	recs := make([]*model.IdempotencyRecord, 0, len(ir.storage))
+	for _, r := range ir.storage {
+		rec := r
+		recs = append(recs, &rec)
+	}
+
+	return recs
+}
+
+func (ir *IdempotencyFileRepo) Compact() error {
+	ir.Lock()
+	defer ir.Unlock()
+
+	recs := make([]model.IdempotencyRecord, 0, len(ir.storage))
+	for _, r := range ir.storage {
+		recs = append(recs, r)
+	}
+
+	return ir.journal.compact(recs)
+}
+
+func (ir *IdempotencyFileRepo) Close() error {
+	if err := ir.Compact(); err != nil {
+		return err
+	}
+
+	ir.Lock()
+	defer ir.Unlock()
+
+	return ir.journal.close()
+}
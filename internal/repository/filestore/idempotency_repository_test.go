@@ -0,0 +1,38 @@
+package filestore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/filestore"
+)
+
+func TestIdempotencyFileRepo_ReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewIdempotencyFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	now := time.Now()
+	_ = repo.Put(&model.IdempotencyRecord{Key: "k1", Fingerprint: "fp", StatusCode: 201, Body: []byte(`{"id":"t1"}`), CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	_ = repo.Put(&model.IdempotencyRecord{Key: "k2", Fingerprint: "fp", StatusCode: 201, Body: []byte(`{}`), CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	if err := repo.Delete("k2"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	reopened, err := filestore.NewIdempotencyFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	recs := reopened.List()
+	if len(recs) != 1 || recs[0].Key != "k1" || recs[0].StatusCode != 201 || string(recs[0].Body) != `{"id":"t1"}` {
+		t.Errorf("unexpected recovered records: %+v", recs)
+	}
+}
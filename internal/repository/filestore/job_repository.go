@@ -0,0 +1,165 @@
+package filestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+type jobRecord struct {
+	Op  string              `json:"op"`
+	ID  string              `json:"id,omitempty"`
+	Job *model.RecurringJob `json:"job,omitempty"`
+}
+
+type JobFileRepo struct {
+	storage map[string]model.RecurringJob
+	journal *journal
+	sync.RWMutex
+}
+
+func NewJobFileRepo(dir string) (*JobFileRepo, error) {
+	jr := &JobFileRepo{
+		storage: make(map[string]model.RecurringJob),
+	}
+
+	j, err := openJournal(dir, "jobs", jr.restore, jr.replay)
+	if err != nil {
+		return nil, err
+	}
+	jr.journal = j
+
+	return jr, nil
+}
+
+func (jr *JobFileRepo) restore(data json.RawMessage) error {
+	var jobs []model.RecurringJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return err
+	}
+
+	for _, j := range jobs {
+		jr.storage[j.ID] = j
+	}
+
+	return nil
+}
+
+func (jr *JobFileRepo) replay(data json.RawMessage) error {
+	var rec jobRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+
+	switch rec.Op {
+	case opPut:
+		jr.storage[rec.Job.ID] = *rec.Job
+	case opDelete:
+		delete(jr.storage, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+
+	return nil
+}
+
+func (jr *JobFileRepo) Save(job *model.RecurringJob) error {
+	jr.Lock()
+	defer jr.Unlock()
+	if _, ok := jr.storage[job.ID]; ok {
+		return fmt.Errorf("%w: job already exist", apperrors.ErrAlreadyExists)
+	}
+
+	return jr.put(job)
+}
+
+func (jr *JobFileRepo) Get(id string) (*model.RecurringJob, error) {
+	jr.RLock()
+	defer jr.RUnlock()
+	job, ok := jr.storage[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: job not found", apperrors.ErrNotFound)
+	}
+
+	return &job, nil
+}
+
+func (jr *JobFileRepo) Update(job *model.RecurringJob) error {
+	jr.Lock()
+	defer jr.Unlock()
+	if _, ok := jr.storage[job.ID]; !ok {
+		return fmt.Errorf("%w: job not found", apperrors.ErrNotFound)
+	}
+
+	return jr.put(job)
+}
+
+func (jr *JobFileRepo) put(job *model.RecurringJob) error {
+	if err := jr.journal.append(jobRecord{Op: opPut, Job: job}); err != nil {
+		return err
+	}
+
+	jr.storage[job.ID] = *job
+
+	return nil
+}
+
+func (jr *JobFileRepo) Delete(id string) error {
+	jr.Lock()
+	defer jr.Unlock()
+	if _, ok := jr.storage[id]; !ok {
+		return fmt.Errorf("%w: job not found", apperrors.ErrNotFound)
+	}
+
+	if err := jr.journal.append(jobRecord{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+
+	delete(jr.storage, id)
+
+	return nil
+}
+
+func (jr *JobFileRepo) List() []*model.RecurringJob {
+	jr.RLock()
+	defer jr.RUnlock()
+
+	jobs := make([]*model.RecurringJob, 0, len(jr.storage))
+	for _, j := range jr.storage {
+		job := j
+		jobs = append(jobs, &job)
+	}
+
+	return jobs
+}
+
+func (jr *JobFileRepo) Compact() error {
+	jr.Lock()
+	defer jr.Unlock()
+
+	jobs := make([]model.RecurringJob, 0, len(jr.storage))
+	for _, j := range jr.storage {
+		jobs = append(jobs, j)
+	}
+
+	return jr.journal.compact(jobs)
+}
+
+func (jr *JobFileRepo) Close() error {
+	if err := jr.Compact(); err != nil {
+		return err
+	}
+
+	jr.Lock()
+	defer jr.Unlock()
+
+	return jr.journal.close()
+}
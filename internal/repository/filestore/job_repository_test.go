@@ -0,0 +1,36 @@
+package filestore_test
+
+import (
+	"testing"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/filestore"
+)
+
+func TestJobFileRepo_ReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewJobFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	_ = repo.Save(&model.RecurringJob{ID: "j1", Schedule: "@every 1m", Type: "cleanup"})
+	_ = repo.Save(&model.RecurringJob{ID: "j2", Schedule: "@hourly", Type: "cleanup"})
+	if err := repo.Update(&model.RecurringJob{ID: "j1", Schedule: "@every 1m", Type: "cleanup", Paused: true}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if err := repo.Delete("j2"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	reopened, err := filestore.NewJobFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	jobs := reopened.List()
+	if len(jobs) != 1 || jobs[0].ID != "j1" || !jobs[0].Paused {
+		t.Errorf("unexpected recovered jobs: %+v", jobs)
+	}
+}
@@ -0,0 +1,249 @@
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type entry struct {
+	Seq  uint64          `json:"seq"`
+	Data json.RawMessage `json:"data"`
+}
+
+type snapshot struct {
+	Seq  uint64          `json:"seq"`
+	Data json.RawMessage `json:"data"`
+}
+
+// walFile — то, что journal использует от открытого файла лога.
+type walFile interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// journal — append-only лог мутаций + снапшот состояния.
+// Записи с seq <= seq снапшота при восстановлении пропускаются,
+// поэтому падение между записью снапшота и обрезкой лога безопасно.
+type journal struct {
+	walPath      string
+	snapshotPath string
+	file         walFile
+	seq          uint64
+	records      int
+	// size — длина лога из целых записей; до неё лог обрезается, если
+	// запись не удалась
+	size int64
+	// broken — ошибка, после которой хвост лога не удалось обрезать: до
+	// следующей компакции, переписывающей лог, новые записи не принимаются
+	broken error
+}
+
+func openJournal(dir, name string, restore func(data json.RawMessage) error, apply func(data json.RawMessage) error) (*journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+
+	j := &journal{
+		walPath:      filepath.Join(dir, name+".wal"),
+		snapshotPath: filepath.Join(dir, name+".snapshot.json"),
+	}
+
+	if err := j.loadSnapshot(restore); err != nil {
+		return nil, err
+	}
+
+	if err := j.replay(apply); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(j.walPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open wal: %w", err)
+	}
+	j.file = file
+
+	return j, nil
+}
+
+func (j *journal) loadSnapshot(restore func(data json.RawMessage) error) error {
+	raw, err := os.ReadFile(j.snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	if err := restore(snap.Data); err != nil {
+		return fmt.Errorf("restore snapshot: %w", err)
+	}
+	j.seq = snap.Seq
+
+	return nil
+}
+
+func (j *journal) replay(apply func(data json.RawMessage) error) error {
+	file, err := os.OpenFile(j.walPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("open wal: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// недописанная последняя запись: обрезаем лог до последней целой
+				if err := file.Truncate(offset); err != nil {
+					return fmt.Errorf("truncate torn wal record: %w", err)
+				}
+			} else {
+				offset += int64(len(line))
+			}
+			j.size = offset
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read wal: %w", err)
+		}
+
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("decode wal record at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+
+		if e.Seq <= j.seq {
+			continue
+		}
+
+		if err := apply(e.Data); err != nil {
+			return fmt.Errorf("apply wal record %d: %w", e.Seq, err)
+		}
+		j.seq = e.Seq
+		j.records++
+	}
+}
+
+// append дописывает запись и ждёт fsync. Если запись или fsync не удались,
+// лог обрезается до последней целой записи, чтобы недописанная строка не
+// оказалась в середине лога под следующими записями.
+func (j *journal) append(rec any) error {
+	if j.broken != nil {
+		return fmt.Errorf("wal is unusable until compaction: %w", j.broken)
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode wal record: %w", err)
+	}
+
+	line, err := json.Marshal(entry{Seq: j.seq + 1, Data: data})
+	if err != nil {
+		return fmt.Errorf("encode wal record: %w", err)
+	}
+
+	line = append(line, '\n')
+	if _, err := j.file.Write(line); err != nil {
+		return j.rollback(fmt.Errorf("write wal: %w", err))
+	}
+	if err := j.file.Sync(); err != nil {
+		return j.rollback(fmt.Errorf("sync wal: %w", err))
+	}
+
+	j.seq++
+	j.records++
+	j.size += int64(len(line))
+
+	return nil
+}
+
+// rollback обрезает лог до j.size после неудачной записи. Если и это не
+// удалось, журнал помечается сломанным.
+func (j *journal) rollback(cause error) error {
+	if err := j.file.Truncate(j.size); err != nil {
+		j.broken = cause
+		return errors.Join(cause, fmt.Errorf("truncate wal: %w", err))
+	}
+	if err := j.file.Sync(); err != nil {
+		j.broken = cause
+		return errors.Join(cause, fmt.Errorf("sync wal: %w", err))
+	}
+
+	return cause
+}
+
+func (j *journal) compact(state any) error {
+	if j.records == 0 && j.broken == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	raw, err := json.Marshal(snapshot{Seq: j.seq, Data: data})
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	tmpPath := j.snapshotPath + ".tmp"
+	if err := writeFileSync(tmpPath, raw); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.snapshotPath); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf("close wal: %w", err)
+	}
+	file, err := os.OpenFile(j.walPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("reopen wal: %w", err)
+	}
+	j.file = file
+	j.records = 0
+	j.size = 0
+	j.broken = nil
+
+	return nil
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
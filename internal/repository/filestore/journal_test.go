@@ -0,0 +1,110 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+// faultyFile пропускает в файл не больше limit байт записи, после чего
+// возвращает ошибку; truncateErr ломает и откат.
+type faultyFile struct {
+	*os.File
+	limit       int
+	truncateErr error
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if len(p) > f.limit {
+		n, _ := f.File.Write(p[:f.limit])
+		return n, errors.New("no space left on device")
+	}
+	return f.File.Write(p)
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if f.truncateErr != nil {
+		return f.truncateErr
+	}
+	return f.File.Truncate(size)
+}
+
+func openTestJournal(t *testing.T, dir string) (*journal, []string) {
+	t.Helper()
+
+	var replayed []string
+	j, err := openJournal(dir, "test", func(json.RawMessage) error { return nil }, func(data json.RawMessage) error {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		replayed = append(replayed, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	return j, replayed
+}
+
+func TestJournal_FailedAppendIsTruncated(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := openTestJournal(t, dir)
+
+	if err := j.append("first"); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	file := j.file.(*os.File)
+	j.file = &faultyFile{File: file, limit: 5}
+	if err := j.append("lost"); err == nil {
+		t.Fatal("expected short write to fail")
+	}
+
+	j.file = file
+	if err := j.append("second"); err != nil {
+		t.Fatalf("append after failed write must succeed: %v", err)
+	}
+	_ = j.close()
+
+	reopened, replayed := openTestJournal(t, dir)
+	defer reopened.close()
+	if len(replayed) != 2 || replayed[0] != "first" || replayed[1] != "second" {
+		t.Errorf("replayed %v, want [first second]", replayed)
+	}
+}
+
+func TestJournal_BrokenUntilCompaction(t *testing.T) {
+	dir := t.TempDir()
+	j, _ := openTestJournal(t, dir)
+
+	if err := j.append("first"); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	file := j.file.(*os.File)
+	j.file = &faultyFile{File: file, limit: 5, truncateErr: errors.New("read-only file system")}
+	if err := j.append("lost"); err == nil {
+		t.Fatal("expected short write to fail")
+	}
+
+	j.file = file
+	if err := j.append("second"); err == nil {
+		t.Fatal("journal with a torn tail must refuse appends")
+	}
+
+	if err := j.compact([]string{"first"}); err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+	if err := j.append("third"); err != nil {
+		t.Fatalf("append after compaction must succeed: %v", err)
+	}
+	_ = j.close()
+
+	reopened, replayed := openTestJournal(t, dir)
+	defer reopened.close()
+	if len(replayed) != 1 || replayed[0] != "third" {
+		t.Errorf("replayed %v, want [third]", replayed)
+	}
+}
@@ -0,0 +1,74 @@
+package filestore
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+// PauseFileRepo хранит состояние паузы; каждая запись журнала — состояние
+// целиком, поэтому при восстановлении побеждает последняя.
+type PauseFileRepo struct {
+	state   model.PauseState
+	journal *journal
+	sync.RWMutex
+}
+
+func NewPauseFileRepo(dir string) (*PauseFileRepo, error) {
+	pr := &PauseFileRepo{}
+
+	j, err := openJournal(dir, "pauses", pr.restore, pr.restore)
+	if err != nil {
+		return nil, err
+	}
+	pr.journal = j
+
+	return pr, nil
+}
+
+func (pr *PauseFileRepo) restore(data json.RawMessage) error {
+	return json.Unmarshal(data, &pr.state)
+}
+
+func (pr *PauseFileRepo) Get() model.PauseState {
+	pr.RLock()
+	defer pr.RUnlock()
+
+	state := pr.state
+	state.Types = slices.Clone(state.Types)
+	return state
+}
+
+func (pr *PauseFileRepo) Save(state model.PauseState) error {
+	pr.Lock()
+	defer pr.Unlock()
+
+	state.Types = slices.Clone(state.Types)
+	if err := pr.journal.append(state); err != nil {
+		return err
+	}
+
+	pr.state = state
+
+	return nil
+}
+
+func (pr *PauseFileRepo) Compact() error {
+	pr.Lock()
+	defer pr.Unlock()
+
+	return pr.journal.compact(pr.state)
+}
+
+func (pr *PauseFileRepo) Close() error {
+	if err := pr.Compact(); err != nil {
+		return err
+	}
+
+	pr.Lock()
+	defer pr.Unlock()
+
+	return pr.journal.close()
+}
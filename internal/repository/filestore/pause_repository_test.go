@@ -0,0 +1,42 @@
+package filestore_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/filestore"
+)
+
+func TestPauseFileRepo_ReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewPauseFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if state := repo.Get(); state.Active() {
+		t.Fatalf("fresh repo must not be paused, got %+v", state)
+	}
+
+	_ = repo.Save(model.PauseState{All: true, Types: []string{"email"}})
+	_ = repo.Save(model.PauseState{Types: []string{"email", "sms"}})
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	reopened, err := filestore.NewPauseFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	_ = reopened.Save(model.PauseState{Types: []string{"sms"}})
+
+	// последняя запись идёт в WAL поверх снапшота, сделанного при Close
+	again, err := filestore.NewPauseFileRepo(dir)
+	if err != nil {
+		t.Fatalf("second reopen failed: %v", err)
+	}
+	if state := again.Get(); state.All || !slices.Equal(state.Types, []string{"sms"}) {
+		t.Errorf("unexpected recovered state: %+v", state)
+	}
+}
@@ -0,0 +1,266 @@
+package filestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+const (
+	opSave          = "save"
+	opStatus        = "status"
+	opIncAttempts   = "inc_attempts"
+	opResetAttempts = "reset_attempts"
+	opUpdate        = "update"
+)
+
+type taskRecord struct {
+	Op     string           `json:"op"`
+	ID     string           `json:"id,omitempty"`
+	Task   *model.Task      `json:"task,omitempty"`
+	Status model.TaskStatus `json:"status,omitempty"`
+}
+
+type TaskFileRepo struct {
+	storage map[string]*model.Task
+	journal *journal
+	sync.RWMutex
+}
+
+func NewTaskFileRepo(dir string) (*TaskFileRepo, error) {
+	tr := &TaskFileRepo{
+		storage: make(map[string]*model.Task, 10),
+	}
+
+	j, err := openJournal(dir, "tasks", tr.restore, tr.replay)
+	if err != nil {
+		return nil, err
+	}
+	tr.journal = j
+
+	return tr, nil
+}
+
+func (tr *TaskFileRepo) restore(data json.RawMessage) error {
+	var tasks []*model.Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		tr.storage[t.ID] = t
+	}
+
+	return nil
+}
+
+func (tr *TaskFileRepo) replay(data json.RawMessage) error {
+	var rec taskRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+
+	return tr.apply(rec)
+}
+
+func (tr *TaskFileRepo) apply(rec taskRecord) error {
+	switch rec.Op {
+	case opSave, opUpdate:
+		tr.storage[rec.Task.ID] = rec.Task
+	case opStatus:
+		if task, ok := tr.storage[rec.ID]; ok {
+			task.Status = rec.Status
+		}
+	case opIncAttempts:
+		if task, ok := tr.storage[rec.ID]; ok {
+			task.Attempts += 1
+		}
+	case opResetAttempts:
+		if task, ok := tr.storage[rec.ID]; ok {
+			task.Attempts = 0
+		}
+	case opDelete:
+		delete(tr.storage, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+
+	return nil
+}
+
+func (tr *TaskFileRepo) Save(task *model.Task) error {
+	tr.Lock()
+	defer tr.Unlock()
+	if _, ok := tr.storage[task.ID]; ok {
+		return fmt.Errorf("%w: task already exist", apperrors.ErrAlreadyExists)
+	}
+
+	if err := tr.journal.append(taskRecord{Op: opSave, Task: task}); err != nil {
+		return err
+	}
+
+	tr.storage[task.ID] = task.Clone()
+
+	return nil
+}
+
+func (tr *TaskFileRepo) Get(id string) (*model.Task, error) {
+	tr.RLock()
+	defer tr.RUnlock()
+	taskPtr, ok := tr.storage[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	return taskPtr.Clone(), nil
+}
+
+func (tr *TaskFileRepo) UpdateStatus(id string, status model.TaskStatus) error {
+	tr.Lock()
+	defer tr.Unlock()
+
+	task, ok := tr.storage[id]
+	if !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	if err := tr.journal.append(taskRecord{Op: opStatus, ID: id, Status: status}); err != nil {
+		return err
+	}
+
+	task.Status = status
+
+	return nil
+}
+
+func (tr *TaskFileRepo) IncAttempts(id string) error {
+	tr.Lock()
+	defer tr.Unlock()
+
+	task, ok := tr.storage[id]
+	if !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	if err := tr.journal.append(taskRecord{Op: opIncAttempts, ID: id}); err != nil {
+		return err
+	}
+
+	task.Attempts += 1
+
+	return nil
+}
+
+func (tr *TaskFileRepo) ResetAttempts(id string) error {
+	tr.Lock()
+	defer tr.Unlock()
+
+	task, ok := tr.storage[id]
+	if !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	if err := tr.journal.append(taskRecord{Op: opResetAttempts, ID: id}); err != nil {
+		return err
+	}
+
+	task.Attempts = 0
+
+	return nil
+}
+
+// Update применяет fn к копии задачи и пишет в лог полученное состояние целиком;
+// при успешной записи копия заменяет хранимую задачу.
+func (tr *TaskFileRepo) Update(id string, fn func(task *model.Task)) error {
+	tr.Lock()
+	defer tr.Unlock()
+
+	task, ok := tr.storage[id]
+	if !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	updated := task.Clone()
+	fn(updated)
+
+	if err := tr.journal.append(taskRecord{Op: opUpdate, Task: updated}); err != nil {
+		return err
+	}
+
+	tr.storage[id] = updated
+
+	return nil
+}
+
+func (tr *TaskFileRepo) Delete(id string) error {
+	tr.Lock()
+	defer tr.Unlock()
+	if _, ok := tr.storage[id]; !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	if err := tr.journal.append(taskRecord{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+
+	delete(tr.storage, id)
+
+	return nil
+}
+
+func (tr *TaskFileRepo) List() []*model.Task {
+	tr.RLock()
+	defer tr.RUnlock()
+
+	tasks := make([]*model.Task, 0, len(tr.storage))
+	for _, t := range tr.storage {
+		tasks = append(tasks, t.Clone())
+	}
+
+	return tasks
+}
+
+func (tr *TaskFileRepo) Query(q model.TaskQuery) (model.TaskPage, error) {
+	tr.RLock()
+	defer tr.RUnlock()
+
+	tasks := make([]*model.Task, 0)
+	for _, t := range tr.storage {
+		if q.Match(t) {
+			tasks = append(tasks, t)
+		}
+	}
+
+	page := q.Page(tasks)
+	for i, t := range page.Tasks {
+		page.Tasks[i] = t.Clone()
+	}
+
+	return page, nil
+}
+
+func (tr *TaskFileRepo) Compact() error {
+	tr.Lock()
+	defer tr.Unlock()
+
+	tasks := make([]*model.Task, 0, len(tr.storage))
+	for _, t := range tr.storage {
+		tasks = append(tasks, t)
+	}
+
+	return tr.journal.compact(tasks)
+}
+
+func (tr *TaskFileRepo) Close() error {
+	if err := tr.Compact(); err != nil {
+		return err
+	}
+
+	tr.Lock()
+	defer tr.Unlock()
+
+	return tr.journal.close()
+}
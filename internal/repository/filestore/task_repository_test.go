@@ -0,0 +1,181 @@
+package filestore_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/filestore"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+func TestTaskFileRepo_ReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	if err := repo.Save(&model.Task{ID: "t1", Type: "email", Payload: "data", MaxRetries: 3, Status: model.StatusQueued}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := repo.UpdateStatus("t1", model.StatusRunning); err != nil {
+		t.Fatalf("update status failed: %v", err)
+	}
+	if err := repo.IncAttempts("t1"); err != nil {
+		t.Fatalf("inc attempts failed: %v", err)
+	}
+
+	// без Close: имитация падения процесса
+	reopened, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	got, err := reopened.Get("t1")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.Status != model.StatusRunning || got.Attempts != 1 || got.Payload != "data" {
+		t.Errorf("unexpected recovered task: %+v", got)
+	}
+}
+
+func TestTaskFileRepo_CompactionKeepsState(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	_ = repo.Save(&model.Task{ID: "t1", Type: "email", MaxRetries: 3})
+	_ = repo.IncAttempts("t1")
+
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "tasks.wal"))
+	if err != nil {
+		t.Fatalf("stat wal failed: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("wal must be truncated after compaction, size = %d", info.Size())
+	}
+
+	_ = repo.IncAttempts("t1")
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	reopened, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	got, err := reopened.Get("t1")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", got.Attempts)
+	}
+}
+
+func TestTaskFileRepo_IgnoresTornTail(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	_ = repo.Save(&model.Task{ID: "t1", Type: "email"})
+
+	f, err := os.OpenFile(filepath.Join(dir, "tasks.wal"), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open wal failed: %v", err)
+	}
+	_, _ = f.WriteString(`{"seq":2,"data":{"op":"inc_att`)
+	_ = f.Close()
+
+	reopened, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	got, err := reopened.Get("t1")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.Attempts != 0 {
+		t.Errorf("torn record must be ignored, attempts = %d", got.Attempts)
+	}
+
+	if err := reopened.IncAttempts("t1"); err != nil {
+		t.Fatalf("inc attempts after recovery failed: %v", err)
+	}
+}
+
+func TestTaskFileRepo_UpdateIsDurable(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	task := &model.Task{ID: "t1", Type: "email"}
+	_ = repo.Save(task)
+
+	if err := repo.Update("t1", func(t *model.Task) {
+		t.Status = model.StatusDone
+		t.Result = []byte(`{"sent":true}`)
+		t.History = append(t.History, model.Attempt{Number: 1, WorkerID: 2})
+	}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	if updated, _ := repo.Get("t1"); updated.Status != model.StatusDone || len(updated.History) != 1 {
+		t.Errorf("update must be visible through Get, got %+v", updated)
+	}
+	if task.Status != "" || len(task.History) != 0 {
+		t.Errorf("update must not touch the saved task, got %+v", task)
+	}
+
+	reopened, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	got, _ := reopened.Get("t1")
+	if got.Status != model.StatusDone || string(got.Result) != `{"sent":true}` || len(got.History) != 1 || got.History[0].WorkerID != 2 {
+		t.Errorf("unexpected recovered task: %+v", got)
+	}
+}
+
+func TestTaskFileRepo_DeleteIsDurable(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	_ = repo.Save(&model.Task{ID: "t1", Type: "email"})
+	if err := repo.Delete("t1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	reopened, err := filestore.NewTaskFileRepo(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+
+	if _, err := reopened.Get("t1"); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("deleted task must stay deleted after replay, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package inmemory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type DeadLetterInMemoryRepo struct {
+	storage map[string]model.DeadLetter
+	sync.RWMutex
+}
+
+func NewDeadLetterInMemoryRepo() *DeadLetterInMemoryRepo {
+	return &DeadLetterInMemoryRepo{
+		storage: make(map[string]model.DeadLetter),
+	}
+}
+
+func (dr *DeadLetterInMemoryRepo) Save(dl *model.DeadLetter) error {
+	dr.Lock()
+	defer dr.Unlock()
+
+	dr.storage[dl.Task.ID] = *dl
+
+	return nil
+}
+
+func (dr *DeadLetterInMemoryRepo) Get(taskID string) (*model.DeadLetter, error) {
+	dr.RLock()
+	defer dr.RUnlock()
+	dl, ok := dr.storage[taskID]
+	if !ok {
+		return nil, fmt.Errorf("%w: dead letter not found", apperrors.ErrNotFound)
+	}
+
+	return &dl, nil
+}
+
+func (dr *DeadLetterInMemoryRepo) Delete(taskID string) error {
+	dr.Lock()
+	defer dr.Unlock()
+	if _, ok := dr.storage[taskID]; !ok {
+		return fmt.Errorf("%w: dead letter not found", apperrors.ErrNotFound)
+	}
+
+	delete(dr.storage, taskID)
+
+	return nil
+}
+
+func (dr *DeadLetterInMemoryRepo) List() []*model.DeadLetter {
+	dr.RLock()
+	defer dr.RUnlock()
+
+	dls := make([]*model.DeadLetter, 0, len(dr.storage))
+	for _, d := range dr.storage {
+		dl := d
+		dls = append(dls, &dl)
+	}
+
+	return dls
+}
@@ -0,0 +1,65 @@
+package inmemory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type IdempotencyInMemoryRepo struct {
+	storage map[string]model.IdempotencyRecord
+	sync.RWMutex
+}
+
+func NewIdempotencyInMemoryRepo() *IdempotencyInMemoryRepo {
+	return &IdempotencyInMemoryRepo{
+		storage: make(map[string]model.IdempotencyRecord),
+	}
+}
+
+func (ir *IdempotencyInMemoryRepo) Put(rec *model.IdempotencyRecord) error {
+	ir.Lock()
+	defer ir.Unlock()
+
+	ir.storage[rec.Key] = *rec
+
+	return nil
+}
+
+func (ir *IdempotencyInMemoryRepo) Get(key string) (*model.IdempotencyRecord, error) {
+	ir.RLock()
+	defer ir.RUnlock()
+	rec, ok := ir.storage[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: idempotency key not found", apperrors.ErrNotFound)
+	}
+
+	return &rec, nil
+}
+
+func (ir *IdempotencyInMemoryRepo) Delete(key string) error {
+	ir.Lock()
+	defer ir.Unlock()
+	if _, ok := ir.storage[key]; !ok {
+		return fmt.Errorf("%w: idempotency key not found", apperrors.ErrNotFound)
+	}
+
+	delete(ir.storage, key)
+
+	return nil
+}
+
+func (ir *IdempotencyInMemoryRepo) List() []*model.IdempotencyRecord {
+	ir.RLock()
+	defer ir.RUnlock()
+
+	recs := make([]*model.IdempotencyRecord, 0, len(ir.storage))
+	for _, r := range ir.storage {
+		rec := r
+		recs = append(recs, &rec)
+	}
+
+	return recs
+}
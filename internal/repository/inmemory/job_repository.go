@@ -0,0 +1,80 @@
+package inmemory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+type JobInMemoryRepo struct {
+	storage map[string]model.RecurringJob
+	sync.RWMutex
+}
+
+func NewJobInMemoryRepo() *JobInMemoryRepo {
+	return &JobInMemoryRepo{
+		storage: make(map[string]model.RecurringJob),
+	}
+}
+
+func (jr *JobInMemoryRepo) Save(job *model.RecurringJob) error {
+	jr.Lock()
+	defer jr.Unlock()
+	if _, ok := jr.storage[job.ID]; ok {
+		return fmt.Errorf("%w: job already exist", apperrors.ErrAlreadyExists)
+	}
+
+	jr.storage[job.ID] = *job
+
+	return nil
+}
+
+func (jr *JobInMemoryRepo) Get(id string) (*model.RecurringJob, error) {
+	jr.RLock()
+	defer jr.RUnlock()
+	job, ok := jr.storage[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: job not found", apperrors.ErrNotFound)
+	}
+
+	return &job, nil
+}
+
+func (jr *JobInMemoryRepo) Update(job *model.RecurringJob) error {
+	jr.Lock()
+	defer jr.Unlock()
+	if _, ok := jr.storage[job.ID]; !ok {
+		return fmt.Errorf("%w: job not found", apperrors.ErrNotFound)
+	}
+
+	jr.storage[job.ID] = *job
+
+	return nil
+}
+
+func (jr *JobInMemoryRepo) Delete(id string) error {
+	jr.Lock()
+	defer jr.Unlock()
+	if _, ok := jr.storage[id]; !ok {
+		return fmt.Errorf("%w: job not found", apperrors.ErrNotFound)
+	}
+
+	delete(jr.storage, id)
+
+	return nil
+}
+
+func (jr *JobInMemoryRepo) List() []*model.RecurringJob {
+	jr.RLock()
+	defer jr.RUnlock()
+
+	jobs := make([]*model.RecurringJob, 0, len(jr.storage))
+	for _, j := range jr.storage {
+		job := j
+		jobs = append(jobs, &job)
+	}
+
+	return jobs
+}
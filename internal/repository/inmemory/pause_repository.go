@@ -0,0 +1,36 @@
+package inmemory
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+type PauseInMemoryRepo struct {
+	state model.PauseState
+	sync.RWMutex
+}
+
+func NewPauseInMemoryRepo() *PauseInMemoryRepo {
+	return &PauseInMemoryRepo{}
+}
+
+func (pr *PauseInMemoryRepo) Get() model.PauseState {
+	pr.RLock()
+	defer pr.RUnlock()
+
+	state := pr.state
+	state.Types = slices.Clone(state.Types)
+	return state
+}
+
+func (pr *PauseInMemoryRepo) Save(state model.PauseState) error {
+	pr.Lock()
+	defer pr.Unlock()
+
+	state.Types = slices.Clone(state.Types)
+	pr.state = state
+
+	return nil
+}
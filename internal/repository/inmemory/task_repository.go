@@ -26,7 +26,7 @@ func (tr *TaskInMemoryRepo) Save(task *model.Task) error {
 		return fmt.Errorf("%w: task already exist", apperrors.ErrAlreadyExists)
 	}
 
-	tr.storage[task.ID] = task
+	tr.storage[task.ID] = task.Clone()
 
 	return nil
 }
@@ -39,7 +39,7 @@ func (tr *TaskInMemoryRepo) Get(id string) (*model.Task, error) {
 		return nil, fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
 	}
 
-	return taskPtr, nil
+	return taskPtr.Clone(), nil
 }
 
 func (tr *TaskInMemoryRepo) UpdateStatus(id string, status model.TaskStatus) error {
@@ -70,14 +70,77 @@ func (tr *TaskInMemoryRepo) IncAttempts(id string) error {
 	return nil
 }
 
+func (tr *TaskInMemoryRepo) ResetAttempts(id string) error {
+	tr.Lock()
+	defer tr.Unlock()
+
+	task, ok := tr.storage[id]
+	if !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	task.Attempts = 0
+
+	return nil
+}
+
+// Update применяет fn к копии задачи и заменяет ею хранимую: наружу
+// репозиторий отдаёт только копии, так что читатели не видят полуизменённую задачу.
+func (tr *TaskInMemoryRepo) Update(id string, fn func(task *model.Task)) error {
+	tr.Lock()
+	defer tr.Unlock()
+
+	task, ok := tr.storage[id]
+	if !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	updated := task.Clone()
+	fn(updated)
+	tr.storage[id] = updated
+
+	return nil
+}
+
+func (tr *TaskInMemoryRepo) Delete(id string) error {
+	tr.Lock()
+	defer tr.Unlock()
+	if _, ok := tr.storage[id]; !ok {
+		return fmt.Errorf("%w: task not found", apperrors.ErrNotFound)
+	}
+
+	delete(tr.storage, id)
+
+	return nil
+}
+
 func (tr *TaskInMemoryRepo) List() []*model.Task {
 	tr.RLock()
 	defer tr.RUnlock()
 
 	tasks := make([]*model.Task, 0, len(tr.storage))
 	for _, t := range tr.storage {
-		tasks = append(tasks, t)
+		tasks = append(tasks, t.Clone())
 	}
 
 	return tasks
 }
+
+func (tr *TaskInMemoryRepo) Query(q model.TaskQuery) (model.TaskPage, error) {
+	tr.RLock()
+	defer tr.RUnlock()
+
+	tasks := make([]*model.Task, 0)
+	for _, t := range tr.storage {
+		if q.Match(t) {
+			tasks = append(tasks, t)
+		}
+	}
+
+	page := q.Page(tasks)
+	for i, t := range page.Tasks {
+		page.Tasks[i] = t.Clone()
+	}
+
+	return page, nil
+}
@@ -1,10 +1,14 @@
 package inmemory_test
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/folivorra/task_queue/internal/model"
 	"github.com/folivorra/task_queue/internal/repository/inmemory"
+	"github.com/folivorra/task_queue/pkg/apperrors"
 )
 
 func TestTaskRepo_SaveAndGet(t *testing.T) {
@@ -43,3 +47,46 @@ func TestTaskRepo_UpdateStatus(t *testing.T) {
 		t.Errorf("status = %s, want %s", got.Status, model.StatusRunning)
 	}
 }
+
+func TestTaskRepo_Query(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, status := range []model.TaskStatus{model.StatusDone, model.StatusFailed, model.StatusDone, model.StatusDone, model.StatusQueued} {
+		_ = repo.Save(&model.Task{
+			ID:        fmt.Sprintf("t%d", i),
+			Status:    status,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	q, err := model.TaskQuery{
+		Statuses:      []model.TaskStatus{model.StatusDone},
+		CreatedBefore: ptrTime(base.Add(4 * time.Minute)),
+		Sort:          model.SortCreatedDesc,
+		Limit:         2,
+	}.Normalize()
+	if err != nil {
+		t.Fatalf("normalize failed: %v", err)
+	}
+
+	first, _ := repo.Query(q)
+	if len(first.Tasks) != 2 || first.Tasks[0].ID != "t3" || first.Tasks[1].ID != "t2" || first.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	q.Cursor = first.NextCursor
+	second, _ := repo.Query(q)
+	if len(second.Tasks) != 1 || second.Tasks[0].ID != "t0" || second.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+
+	q.Sort = model.SortID
+	if _, err := q.Normalize(); !errors.Is(err, apperrors.ErrInvalidData) {
+		t.Errorf("cursor from another sort must be rejected, got %v", err)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
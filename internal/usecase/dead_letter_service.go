@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"errors"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+type DeadLetterRepo interface {
+	Save(dl *model.DeadLetter) error
+	Get(taskID string) (*model.DeadLetter, error)
+	Delete(taskID string) error
+	List() []*model.DeadLetter
+}
+
+type DeadLetterService struct {
+	repo  DeadLetterRepo
+	tasks *TaskService
+}
+
+func NewDeadLetterService(repo DeadLetterRepo, tasks *TaskService) *DeadLetterService {
+	return &DeadLetterService{
+		repo:  repo,
+		tasks: tasks,
+	}
+}
+
+func (ds *DeadLetterService) Bury(task *model.Task) (*model.DeadLetter, error) {
+	now := ds.tasks.Now()
+
+	var errs []model.AttemptError
+	for _, a := range task.History {
+		if a.Error == "" {
+			continue
+		}
+
+		at := a.StartedAt
+		if a.FinishedAt != nil {
+			at = *a.FinishedAt
+		}
+		errs = append(errs, model.AttemptError{Attempt: a.Number, Error: a.Error, At: at})
+	}
+
+	// ошибка вне попытки (например, дедлайн истёк в ожидании ретрая)
+	if task.LastError != "" && (len(errs) == 0 || errs[len(errs)-1].Error != task.LastError) {
+		errs = append(errs, model.AttemptError{Attempt: task.Attempts, Error: task.LastError, At: now})
+	}
+
+	dl := &model.DeadLetter{
+		Task:   *task,
+		Errors: errs,
+		DeadAt: now,
+	}
+
+	if err := ds.repo.Save(dl); err != nil {
+		return nil, err
+	}
+
+	return dl, nil
+}
+
+func (ds *DeadLetterService) Get(taskID string) (*model.DeadLetter, error) {
+	return ds.repo.Get(taskID)
+}
+
+func (ds *DeadLetterService) List() []*model.DeadLetter {
+	return ds.repo.List()
+}
+
+// Requeue возвращает задачу из dead letters и передаёт её admit. Если admit
+// отказал, задача и запись dead letter остаются в прежнем состоянии.
+func (ds *DeadLetterService) Requeue(taskID string, admit func(task *model.Task) error) (*model.Task, error) {
+	if _, err := ds.repo.Get(taskID); err != nil {
+		return nil, err
+	}
+
+	prev, err := ds.tasks.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ds.tasks.Revive(taskID); err != nil {
+		return nil, err
+	}
+
+	task, err := ds.tasks.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := admit(task); err != nil {
+		if rbErr := ds.tasks.Restore(prev); rbErr != nil {
+			return nil, errors.Join(err, rbErr)
+		}
+		return nil, err
+	}
+
+	if err := ds.repo.Delete(taskID); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// RequeueAll возвращает все dead letters; задачи, которые admit не принял,
+// остаются на месте и перечислены в Failed. Ошибка объединяет причины
+// отказов, чтобы вызывающий мог их классифицировать.
+func (ds *DeadLetterService) RequeueAll(admit func(task *model.Task) error) (model.RequeueResult, error) {
+	result := model.RequeueResult{
+		Requeued: []*model.Task{},
+		Failed:   []model.RequeueFailure{},
+	}
+	var errs []error
+
+	for _, dl := range ds.repo.List() {
+		task, err := ds.Requeue(dl.Task.ID, admit)
+		if err != nil {
+			errs = append(errs, err)
+			result.Failed = append(result.Failed, model.RequeueFailure{TaskID: dl.Task.ID, Error: err.Error()})
+			continue
+		}
+		result.Requeued = append(result.Requeued, task)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+func (ds *DeadLetterService) Purge(taskID string) error {
+	return ds.repo.Delete(taskID)
+}
+
+func (ds *DeadLetterService) PurgeAll() (int, error) {
+	var (
+		purged int
+		errs   []error
+	)
+
+	for _, dl := range ds.repo.List() {
+		if err := ds.repo.Delete(dl.Task.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/inmemory"
+	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+func TestDeadLetterService_RequeueAllReportsFailures(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	tasks := usecase.NewTaskService(repo)
+	tasks.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+	deadLetters := usecase.NewDeadLetterService(inmemory.NewDeadLetterInMemoryRepo(), tasks)
+
+	for _, id := range []string{"a", "b"} {
+		_ = tasks.Save(&model.Task{ID: id, Type: "noop", MaxRetries: 1})
+		_ = repo.Update(id, func(t *model.Task) {
+			t.Status = model.StatusFailed
+			t.Attempts = 1
+			t.LastError = "downstream unavailable"
+		})
+		task, _ := tasks.Get(id)
+		if _, err := deadLetters.Bury(task); err != nil {
+			t.Fatalf("bury %s failed: %v", id, err)
+		}
+	}
+
+	result, err := deadLetters.RequeueAll(func(task *model.Task) error {
+		if task.ID == "b" {
+			return apperrors.ErrOverloaded
+		}
+		return nil
+	})
+	if !errors.Is(err, apperrors.ErrOverloaded) {
+		t.Errorf("expected ErrOverloaded in the joined error, got %v", err)
+	}
+	if len(result.Requeued) != 1 || result.Requeued[0].ID != "a" {
+		t.Errorf("unexpected requeued tasks %+v", result.Requeued)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].TaskID != "b" || result.Failed[0].Error == "" {
+		t.Errorf("failed dead letters must be reported with a reason, got %+v", result.Failed)
+	}
+	if _, err := deadLetters.Get("b"); err != nil {
+		t.Errorf("rejected dead letter must stay buried, got %v", err)
+	}
+	if _, err := deadLetters.Get("a"); err == nil {
+		t.Error("requeued dead letter must be removed")
+	}
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+const (
+	DefaultEventHistory = 1024
+	// subscriberBuffer — сколько событий может накопиться у медленного
+	// подписчика, прежде чем его отключат.
+	subscriberBuffer = 256
+)
+
+// EventBus рассылает события о задачах подписчикам и хранит последние
+// события в кольцевом буфере, чтобы переподключившийся клиент мог
+// дочитать пропущенное.
+type EventBus struct {
+	mu      sync.Mutex
+	history []model.TaskEvent
+	head    int
+	size    int
+	seq     uint64
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+func NewEventBus(history int) *EventBus {
+	if history <= 0 {
+		history = DefaultEventHistory
+	}
+
+	return &EventBus{
+		history: make([]model.TaskEvent, history),
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription получает события, подходящие под фильтр. Канал Events
+// закрывается при Close, остановке шины или если подписчик не успевает
+// вычитывать события; в последнем случае клиенту стоит переподключиться
+// с последним полученным Seq.
+type Subscription struct {
+	bus    *EventBus
+	filter model.EventFilter
+	ch     chan model.TaskEvent
+}
+
+func (s *Subscription) Events() <-chan model.TaskEvent {
+	return s.ch
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	s.bus.remove(s)
+}
+
+// Publish присваивает событию Seq, запоминает его и рассылает подписчикам.
+func (b *EventBus) Publish(ev model.TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	ev.Seq = b.seq
+
+	b.history[(b.head+b.size)%len(b.history)] = ev
+	if b.size < len(b.history) {
+		b.size++
+	} else {
+		b.head = (b.head + 1) % len(b.history)
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.Match(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *EventBus) Subscribe(filter model.EventFilter) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribe(filter)
+}
+
+// SubscribeFrom подписывает на события после lastSeq и возвращает уже
+// накопленные из них. complete == false, если часть событий после lastSeq
+// вытеснена из буфера или lastSeq выдан до перезапуска процесса.
+func (b *EventBus) SubscribeFrom(filter model.EventFilter, lastSeq uint64) (*Subscription, []model.TaskEvent, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		replay []model.TaskEvent
+		oldest = b.seq - uint64(b.size) + 1
+	)
+	for i := 0; i < b.size; i++ {
+		ev := b.history[(b.head+i)%len(b.history)]
+		if ev.Seq > lastSeq && filter.Match(ev) {
+			replay = append(replay, ev)
+		}
+	}
+	complete := lastSeq <= b.seq && lastSeq+1 >= oldest
+
+	return b.subscribe(filter), replay, complete
+}
+
+// Close отключает всех подписчиков; события продолжают копиться в буфере.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// subscribe и remove вызываются под b.mu.
+func (b *EventBus) subscribe(filter model.EventFilter) *Subscription {
+	sub := &Subscription{
+		bus:    b,
+		filter: filter,
+		ch:     make(chan model.TaskEvent, subscriberBuffer),
+	}
+	if b.closed {
+		close(sub.ch)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+
+	return sub
+}
+
+func (b *EventBus) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.ch)
+}
@@ -0,0 +1,149 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/inmemory"
+	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/metrics"
+)
+
+func TestEventBus_ResumeFromHistory(t *testing.T) {
+	bus := usecase.NewEventBus(3)
+	for _, id := range []string{"t1", "t2", "t3", "t4"} {
+		bus.Publish(model.TaskEvent{TaskID: id, Status: model.StatusQueued})
+	}
+
+	sub, replay, complete := bus.SubscribeFrom(model.EventFilter{}, 2)
+	defer sub.Close()
+	if !complete || len(replay) != 2 || replay[0].Seq != 3 || replay[1].TaskID != "t4" {
+		t.Fatalf("unexpected replay %+v (complete=%v)", replay, complete)
+	}
+
+	if _, replay, complete := bus.SubscribeFrom(model.EventFilter{}, 0); complete || len(replay) != 3 {
+		t.Errorf("evicted events must be reported, got %d events (complete=%v)", len(replay), complete)
+	}
+	if _, _, complete := bus.SubscribeFrom(model.EventFilter{}, 99); complete {
+		t.Error("seq from a previous process must be reported as incomplete")
+	}
+
+	bus.Publish(model.TaskEvent{TaskID: "t5", Status: model.StatusDone})
+	if ev := <-sub.Events(); ev.Seq != 5 || ev.TaskID != "t5" {
+		t.Errorf("unexpected live event %+v", ev)
+	}
+}
+
+func TestEventBus_FilterAndSlowSubscriber(t *testing.T) {
+	bus := usecase.NewEventBus(0)
+
+	filtered := bus.Subscribe(model.EventFilter{Statuses: []model.TaskStatus{model.StatusDone}})
+	defer filtered.Close()
+	slow := bus.Subscribe(model.EventFilter{})
+
+	for i := 0; i < 1000; i++ {
+		bus.Publish(model.TaskEvent{TaskID: "t1", Status: model.StatusRunning})
+	}
+	bus.Publish(model.TaskEvent{TaskID: "t1", Status: model.StatusDone})
+
+	if ev := <-filtered.Events(); ev.Status != model.StatusDone {
+		t.Errorf("filter must drop other statuses, got %+v", ev)
+	}
+
+	n := 0
+	for range slow.Events() {
+		n++
+	}
+	if n == 0 || n >= 1001 {
+		t.Errorf("slow subscriber must be disconnected after its buffer fills, got %d events", n)
+	}
+}
+
+func TestTaskService_PublishesTransitions(t *testing.T) {
+	bus := usecase.NewEventBus(0)
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo(), usecase.WithEvents(bus))
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+
+	sub := bus.Subscribe(model.EventFilter{TaskIDs: []string{"t1"}})
+	defer sub.Close()
+
+	task := &model.Task{ID: "t1", Type: "noop", MaxRetries: 1}
+	_ = service.Save(task)
+	_ = service.Save(&model.Task{ID: "t2", Type: "noop"})
+	_, _ = service.ScheduleRetry("t1")
+	_ = service.MarkQueued("t1")
+	_ = service.HandleTask(context.Background(), task)
+
+	want := []model.TaskStatus{model.StatusQueued, model.StatusRetrying, model.StatusQueued, model.StatusRunning, model.StatusDone}
+	for i, status := range want {
+		ev := <-sub.Events()
+		if ev.Status != status || ev.TaskID != "t1" || ev.Type != "noop" {
+			t.Fatalf("event %d: got %+v, want status %s", i, ev, status)
+		}
+	}
+}
+
+func TestTaskService_DiscardPublishesRejected(t *testing.T) {
+	bus := usecase.NewEventBus(0)
+	registry := metrics.NewRegistry()
+	m := usecase.NewMetrics(registry)
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo(), usecase.WithEvents(bus), usecase.WithMetrics(m))
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+
+	sub := bus.Subscribe(model.EventFilter{TaskIDs: []string{"t1"}})
+	defer sub.Close()
+
+	_ = service.Save(&model.Task{ID: "t1", Type: "noop"})
+	if err := service.Discard("t1"); err != nil {
+		t.Fatalf("discard failed: %v", err)
+	}
+
+	for i, status := range []model.TaskStatus{model.StatusQueued, model.StatusRejected} {
+		if ev := <-sub.Events(); ev.Status != status {
+			t.Fatalf("event %d: got %s, want %s", i, ev.Status, status)
+		}
+	}
+	if got := m.Enqueued.Value("noop"); got != 0 {
+		t.Errorf("discarded task must not be counted as enqueued, got %v", got)
+	}
+	if got := m.Rejected.Value("noop"); got != 1 {
+		t.Errorf("rejected = %v, want 1", got)
+	}
+}
+
+func TestTaskService_SaveAllRollbackIsQuiet(t *testing.T) {
+	bus := usecase.NewEventBus(0)
+	registry := metrics.NewRegistry()
+	m := usecase.NewMetrics(registry)
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo(), usecase.WithEvents(bus), usecase.WithMetrics(m))
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+
+	sub := bus.Subscribe(model.EventFilter{})
+	defer sub.Close()
+
+	i, err := service.SaveAll([]*model.Task{
+		{ID: "t1", Type: "noop"},
+		{ID: "t2", Type: "unknown"},
+	})
+	if err == nil || i != 1 {
+		t.Fatalf("expected failure on task 1, got %d, %v", i, err)
+	}
+	if _, err := service.Get("t1"); err == nil {
+		t.Error("saved part of a failed batch must be rolled back")
+	}
+	if got := m.Rejected.Value("noop"); got != 0 {
+		t.Errorf("rolled back batch must not count as rejected by admission, got %v", got)
+	}
+
+	bus.Publish(model.TaskEvent{TaskID: "marker", Status: model.StatusDone})
+	if ev := <-sub.Events(); ev.TaskID != "marker" {
+		t.Errorf("rolled back batch must not publish events, got %+v", ev)
+	}
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+	"github.com/folivorra/task_queue/pkg/clock"
+)
+
+const maxIdempotencyKeyLen = 255
+
+type IdempotencyRepo interface {
+	Put(rec *model.IdempotencyRecord) error
+	Get(key string) (*model.IdempotencyRecord, error)
+	Delete(key string) error
+	List() []*model.IdempotencyRecord
+}
+
+// IdempotencyService хранит ответы на запросы с Idempotency-Key в течение ttl.
+// Запросы, которые ещё выполняются, держатся только в памяти: после рестарта
+// незавершённый запрос можно просто повторить.
+type IdempotencyService struct {
+	repo     IdempotencyRepo
+	ttl      time.Duration
+	clock    clock.Clock
+	inFlight map[string]string
+	mu       sync.Mutex
+}
+
+func NewIdempotencyService(repo IdempotencyRepo, ttl time.Duration, c clock.Clock) *IdempotencyService {
+	return &IdempotencyService{
+		repo:     repo,
+		ttl:      ttl,
+		clock:    c,
+		inFlight: make(map[string]string),
+	}
+}
+
+// Begin резервирует ключ. Если по ключу уже сохранён ответ на такой же
+// запрос, он возвращается для повтора; другой запрос с тем же ключом
+// отклоняется с ErrUnprocessable.
+func (is *IdempotencyService) Begin(key, fingerprint string) (*model.IdempotencyRecord, error) {
+	if len(key) > maxIdempotencyKeyLen {
+		return nil, fmt.Errorf("%w: idempotency key is longer than %d bytes", apperrors.ErrInvalidData, maxIdempotencyKeyLen)
+	}
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if inFlight, ok := is.inFlight[key]; ok {
+		if inFlight != fingerprint {
+			return nil, fmt.Errorf("%w: idempotency key was used with a different request", apperrors.ErrUnprocessable)
+		}
+		return nil, fmt.Errorf("%w: request with this idempotency key is in progress", apperrors.ErrConflict)
+	}
+
+	rec, err := is.repo.Get(key)
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+	case err != nil:
+		return nil, err
+	case !rec.Expired(is.clock.Now()):
+		if rec.Fingerprint != fingerprint {
+			return nil, fmt.Errorf("%w: idempotency key was used with a different request", apperrors.ErrUnprocessable)
+		}
+		return rec, nil
+	}
+
+	is.inFlight[key] = fingerprint
+
+	return nil, nil
+}
+
+// Complete сохраняет ответ на зарезервированный ключ.
+func (is *IdempotencyService) Complete(key string, statusCode int, body []byte) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	fingerprint, ok := is.inFlight[key]
+	if !ok {
+		return fmt.Errorf("%w: idempotency key is not reserved", apperrors.ErrNotFound)
+	}
+	delete(is.inFlight, key)
+
+	now := is.clock.Now()
+	return is.repo.Put(&model.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		StatusCode:  statusCode,
+		Body:        body,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(is.ttl),
+	})
+}
+
+// Release снимает резерв, не сохраняя ответ, чтобы неуспешный запрос можно было повторить.
+func (is *IdempotencyService) Release(key string) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	delete(is.inFlight, key)
+}
+
+func (is *IdempotencyService) PurgeExpired() (int, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	var (
+		purged int
+		errs   []error
+	)
+
+	now := is.clock.Now()
+	for _, rec := range is.repo.List() {
+		if !rec.Expired(now) {
+			continue
+		}
+		if err := is.repo.Delete(rec.Key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, errors.Join(errs...)
+}
+
+// RunExpiry периодически удаляет истёкшие ключи, пока не отменён ctx.
+func (is *IdempotencyService) RunExpiry(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := is.PurgeExpired(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
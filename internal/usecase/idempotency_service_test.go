@@ -0,0 +1,54 @@
+package usecase_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/repository/inmemory"
+	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+	"github.com/folivorra/task_queue/pkg/clock"
+)
+
+func TestIdempotencyService(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := inmemory.NewIdempotencyInMemoryRepo()
+	service := usecase.NewIdempotencyService(repo, time.Hour, clock.Func(func() time.Time { return now }))
+
+	if rec, err := service.Begin("k", "fp1"); rec != nil || err != nil {
+		t.Fatalf("first request must reserve the key, got %v %v", rec, err)
+	}
+
+	if _, err := service.Begin("k", "fp1"); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("concurrent duplicate must conflict, got %v", err)
+	}
+	if _, err := service.Begin("k", "fp2"); !errors.Is(err, apperrors.ErrUnprocessable) {
+		t.Errorf("different request must be unprocessable, got %v", err)
+	}
+
+	if err := service.Complete("k", 201, []byte(`{"id":"t1"}`)); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+
+	rec, err := service.Begin("k", "fp1")
+	if err != nil || rec == nil || rec.StatusCode != 201 || string(rec.Body) != `{"id":"t1"}` {
+		t.Fatalf("expected stored response, got %+v %v", rec, err)
+	}
+	if _, err := service.Begin("k", "fp2"); !errors.Is(err, apperrors.ErrUnprocessable) {
+		t.Errorf("different request must be unprocessable, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if purged, err := service.PurgeExpired(); purged != 1 || err != nil {
+		t.Errorf("expected one expired key, got %d %v", purged, err)
+	}
+	if rec, err := service.Begin("k", "fp2"); rec != nil || err != nil {
+		t.Errorf("expired key must be reusable, got %v %v", rec, err)
+	}
+
+	service.Release("k")
+	if rec, err := service.Begin("k", "fp3"); rec != nil || err != nil {
+		t.Errorf("released key must be reusable, got %v %v", rec, err)
+	}
+}
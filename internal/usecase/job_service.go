@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+	"github.com/folivorra/task_queue/pkg/cron"
+)
+
+const maxCatchUpRuns = 1000
+
+type JobRepo interface {
+	Save(job *model.RecurringJob) error
+	Get(id string) (*model.RecurringJob, error)
+	Update(job *model.RecurringJob) error
+	Delete(id string) error
+	List() []*model.RecurringJob
+}
+
+type JobService struct {
+	repo  JobRepo
+	tasks *TaskService
+	// mu сериализует изменения заданий между REST и планировщиком,
+	// чтобы тик не затёр паузу, выставленную параллельно
+	mu sync.Mutex
+}
+
+func NewJobService(repo JobRepo, tasks *TaskService) *JobService {
+	return &JobService{
+		repo:  repo,
+		tasks: tasks,
+	}
+}
+
+func (js *JobService) Create(job *model.RecurringJob) error {
+	if job.CatchUp == "" {
+		job.CatchUp = model.CatchUpSkip
+	}
+
+	if err := model.ValidateJob(*job); err != nil {
+		return err
+	}
+
+	if _, err := js.tasks.handler(job.Type); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	next, err := nextRun(job.Schedule, now)
+	if err != nil {
+		return err
+	}
+
+	job.CreatedAt = now
+	job.NextRunAt = &next
+
+	return js.repo.Save(job)
+}
+
+func (js *JobService) Get(id string) (*model.RecurringJob, error) {
+	return js.repo.Get(id)
+}
+
+func (js *JobService) List() []*model.RecurringJob {
+	return js.repo.List()
+}
+
+func (js *JobService) Delete(id string) error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	return js.repo.Delete(id)
+}
+
+func (js *JobService) Pause(id string) (*model.RecurringJob, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, err := js.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Paused = true
+	if err := js.repo.Update(job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (js *JobService) Resume(id string) (*model.RecurringJob, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, err := js.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// пропущенные за время паузы запуски не догоняем
+	next, err := nextRun(job.Schedule, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	job.Paused = false
+	job.NextRunAt = &next
+	if err := js.repo.Update(job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// CatchUp обрабатывает запуски, пропущенные пока сервис не работал,
+// согласно CatchUp-политике каждого задания.
+func (js *JobService) CatchUp(now time.Time) ([]*model.Task, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	var (
+		created []*model.Task
+		errs    []error
+	)
+
+	for _, job := range js.repo.List() {
+		if job.Paused || job.NextRunAt == nil || job.NextRunAt.After(now) {
+			continue
+		}
+
+		schedule, err := cron.Parse(job.Schedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", job.ID, err))
+			continue
+		}
+
+		var missed []time.Time
+		for at := *job.NextRunAt; !at.IsZero() && !at.After(now) && len(missed) < maxCatchUpRuns; at = schedule.Next(at) {
+			missed = append(missed, at)
+		}
+
+		switch job.CatchUp {
+		case model.CatchUpSkip:
+			missed = nil
+		case model.CatchUpOnce:
+			missed = missed[len(missed)-1:]
+		}
+
+		tasks, err := js.fire(job, missed, schedule.Next(now))
+		created = append(created, tasks...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return created, errors.Join(errs...)
+}
+
+// Tick материализует задачи для всех наступивших запусков.
+// Если с прошлого тика прошло несколько запусков, они схлопываются в один.
+func (js *JobService) Tick(now time.Time) ([]*model.Task, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	var (
+		created []*model.Task
+		errs    []error
+	)
+
+	for _, job := range js.repo.List() {
+		if job.Paused || job.NextRunAt == nil || job.NextRunAt.After(now) {
+			continue
+		}
+
+		schedule, err := cron.Parse(job.Schedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", job.ID, err))
+			continue
+		}
+
+		tasks, err := js.fire(job, []time.Time{*job.NextRunAt}, schedule.Next(now))
+		created = append(created, tasks...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return created, errors.Join(errs...)
+}
+
+func (js *JobService) fire(job *model.RecurringJob, runs []time.Time, next time.Time) ([]*model.Task, error) {
+	var created []*model.Task
+
+	for _, at := range runs {
+		task := &model.Task{
+			ID:         fmt.Sprintf("%s-%d", job.ID, at.UnixMilli()),
+			Type:       job.Type,
+			Payload:    job.Payload,
+			MaxRetries: job.MaxRetries,
+		}
+
+		// ID детерминирован временем запуска, поэтому повторная
+		// материализация после рестарта упирается в ErrAlreadyExists
+		err := js.tasks.Save(task)
+		if errors.Is(err, apperrors.ErrAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("job %s: %w", job.ID, err)
+		}
+
+		created = append(created, task)
+		job.LastRunAt = &at
+	}
+
+	job.NextRunAt = nil
+	if !next.IsZero() {
+		job.NextRunAt = &next
+	}
+
+	if err := js.repo.Update(job); err != nil {
+		return created, fmt.Errorf("job %s: %w", job.ID, err)
+	}
+
+	return created, nil
+}
+
+func nextRun(spec string, after time.Time) (time.Time, error) {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", apperrors.ErrInvalidData, err)
+	}
+
+	next := schedule.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: schedule never fires", apperrors.ErrInvalidData)
+	}
+
+	return next, nil
+}
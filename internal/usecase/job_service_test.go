@@ -0,0 +1,123 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/internal/repository/inmemory"
+	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+)
+
+func newJobService(t *testing.T) (*usecase.JobService, *inmemory.JobInMemoryRepo) {
+	t.Helper()
+
+	taskService := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	taskService.Register("cleanup", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+
+	jobRepo := inmemory.NewJobInMemoryRepo()
+	return usecase.NewJobService(jobRepo, taskService), jobRepo
+}
+
+func TestJobService_CreateValidates(t *testing.T) {
+	service, _ := newJobService(t)
+
+	for _, job := range []*model.RecurringJob{
+		{ID: "j1", Schedule: "bad spec", Type: "cleanup"},
+		{ID: "j2", Schedule: "@every 1m", Type: "unknown"},
+		{ID: "j3", Schedule: "@every 1m", Type: "cleanup", CatchUp: "sometimes"},
+		{ID: "j4", Schedule: "0 0 30 2 *", Type: "cleanup"},
+	} {
+		if err := service.Create(job); !errors.Is(err, apperrors.ErrInvalidData) {
+			t.Errorf("job %s: expected ErrInvalidData, got %v", job.ID, err)
+		}
+	}
+
+	job := &model.RecurringJob{ID: "ok", Schedule: "*/5 * * * *", Type: "cleanup"}
+	if err := service.Create(job); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if job.CatchUp != model.CatchUpSkip || job.NextRunAt == nil {
+		t.Errorf("unexpected defaults: %+v", job)
+	}
+}
+
+func TestJobService_TickMaterializesTasks(t *testing.T) {
+	service, _ := newJobService(t)
+
+	job := &model.RecurringJob{ID: "j1", Schedule: "@every 1m", Type: "cleanup", Payload: "p"}
+	if err := service.Create(job); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	tasks, err := service.Tick(time.Now())
+	if err != nil || len(tasks) != 0 {
+		t.Fatalf("job must not fire before next_run_at: %v %v", tasks, err)
+	}
+
+	tasks, err = service.Tick(job.NextRunAt.Add(time.Second))
+	if err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Type != "cleanup" || tasks[0].Payload != "p" {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+
+	if _, err := service.Pause("j1"); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	tasks, _ = service.Tick(time.Now().Add(time.Hour))
+	if len(tasks) != 0 {
+		t.Errorf("paused job must not fire, got %d tasks", len(tasks))
+	}
+}
+
+func TestJobService_CatchUpPolicies(t *testing.T) {
+	tests := []struct {
+		policy model.CatchUpPolicy
+		want   int
+	}{
+		{model.CatchUpSkip, 0},
+		{model.CatchUpOnce, 1},
+		{model.CatchUpAll, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			service, repo := newJobService(t)
+
+			job := &model.RecurringJob{ID: "j1", Schedule: "@every 1m", Type: "cleanup", CatchUp: tt.policy}
+			if err := service.Create(job); err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+
+			// имитируем простой: последний запланированный запуск был 5 минут назад
+			missedFrom := time.Now().Add(-5*time.Minute + time.Second)
+			job.NextRunAt = &missedFrom
+			_ = repo.Update(job)
+
+			tasks, err := service.CatchUp(time.Now())
+			if err != nil {
+				t.Fatalf("catch up failed: %v", err)
+			}
+			if len(tasks) != tt.want {
+				t.Errorf("got %d tasks, want %d", len(tasks), tt.want)
+			}
+
+			got, _ := service.Get("j1")
+			if !got.NextRunAt.After(time.Now()) {
+				t.Errorf("next_run_at must move to the future, got %s", got.NextRunAt)
+			}
+
+			again, _ := service.CatchUp(time.Now())
+			if len(again) != 0 {
+				t.Errorf("second catch up must be a no-op, got %d tasks", len(again))
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package usecase
+
+import "github.com/folivorra/task_queue/pkg/metrics"
+
+// Metrics — метрики обработки задач, счётчики и гистограммы размечены типом задачи.
+type Metrics struct {
+	Enqueued        *metrics.CounterVec
+	Rejected        *metrics.CounterVec
+	Succeeded       *metrics.CounterVec
+	Failed          *metrics.CounterVec
+	Retried         *metrics.CounterVec
+	Dead            *metrics.CounterVec
+	BusyWorkers     *metrics.Gauge
+	QueueWait       *metrics.HistogramVec
+	HandlerDuration *metrics.HistogramVec
+}
+
+func NewMetrics(r *metrics.Registry) *Metrics {
+	return &Metrics{
+		Enqueued:        r.NewCounterVec("taskqueue_tasks_enqueued_total", "Tasks admitted to the worker queue.", "type"),
+		Rejected:        r.NewCounterVec("taskqueue_tasks_rejected_total", "Saved tasks rolled back after admission refused them.", "type"),
+		Succeeded:       r.NewCounterVec("taskqueue_tasks_succeeded_total", "Attempts finished successfully.", "type"),
+		Failed:          r.NewCounterVec("taskqueue_tasks_failed_total", "Attempts finished with an error or a timeout.", "type"),
+		Retried:         r.NewCounterVec("taskqueue_tasks_retried_total", "Failed tasks sent back for a retry.", "type"),
+		Dead:            r.NewCounterVec("taskqueue_tasks_dead_total", "Tasks moved to the dead-letter queue.", "type"),
+		BusyWorkers:     r.NewGauge("taskqueue_busy_workers", "Workers currently running a handler."),
+		QueueWait:       r.NewHistogramVec("taskqueue_queue_wait_seconds", "Time from queued_at to the start of an attempt.", metrics.DefBuckets, "type"),
+		HandlerDuration: r.NewHistogramVec("taskqueue_handler_duration_seconds", "Handler execution time per attempt.", metrics.DefBuckets, "type"),
+	}
+}
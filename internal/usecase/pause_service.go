@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/folivorra/task_queue/internal/model"
+)
+
+type PauseRepo interface {
+	Get() model.PauseState
+	Save(state model.PauseState) error
+}
+
+// PauseService хранит, какие задачи не выдаются воркерам. Состояние
+// сохраняется в репозитории и переживает рестарт при STORAGE=file; сам пул
+// узнаёт об изменениях через OnChange.
+type PauseService struct {
+	repo     PauseRepo
+	onChange []func(state model.PauseState)
+	mu       sync.Mutex
+}
+
+func NewPauseService(repo PauseRepo) *PauseService {
+	return &PauseService{
+		repo: repo,
+	}
+}
+
+func (ps *PauseService) State() model.PauseState {
+	return withTypes(ps.repo.Get())
+}
+
+// Pause приостанавливает выдачу задач типа taskType, пустой тип — всех задач.
+func (ps *PauseService) Pause(taskType string) (model.PauseState, error) {
+	return ps.update(func(state *model.PauseState) {
+		switch {
+		case taskType == "":
+			state.All = true
+		case !slices.Contains(state.Types, taskType):
+			state.Types = append(state.Types, taskType)
+			slices.Sort(state.Types)
+		}
+	})
+}
+
+// Resume возобновляет выдачу задач типа taskType; пустой тип снимает все
+// паузы, и общую, и по типам.
+func (ps *PauseService) Resume(taskType string) (model.PauseState, error) {
+	return ps.update(func(state *model.PauseState) {
+		if taskType == "" {
+			*state = model.PauseState{}
+			return
+		}
+		state.Types = slices.DeleteFunc(state.Types, func(t string) bool {
+			return t == taskType
+		})
+	})
+}
+
+// OnChange регистрирует fn, который вызывается после каждого изменения.
+func (ps *PauseService) OnChange(fn func(state model.PauseState)) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.onChange = append(ps.onChange, fn)
+}
+
+func (ps *PauseService) update(fn func(state *model.PauseState)) (model.PauseState, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	state := ps.repo.Get()
+	fn(&state)
+	if err := ps.repo.Save(state); err != nil {
+		return model.PauseState{}, err
+	}
+
+	state = withTypes(state)
+	for _, fn := range ps.onChange {
+		fn(state)
+	}
+
+	return state, nil
+}
+
+// withTypes заменяет nil на пустой список, чтобы в JSON был [], а не null.
+func withTypes(state model.PauseState) model.PauseState {
+	if state.Types == nil {
+		state.Types = []string{}
+	}
+	return state
+}
@@ -2,11 +2,21 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
+	mathrand "math/rand"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/folivorra/task_queue/internal/model"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+	"github.com/folivorra/task_queue/pkg/clock"
+	"github.com/folivorra/task_queue/pkg/metrics"
 )
 
 type TaskRepo interface {
@@ -14,29 +24,226 @@ type TaskRepo interface {
 	Get(id string) (*model.Task, error)
 	UpdateStatus(id string, status model.TaskStatus) error
 	List() []*model.Task
+	Query(q model.TaskQuery) (model.TaskPage, error)
 	IncAttempts(id string) error
+	ResetAttempts(id string) error
+	Update(id string, fn func(task *model.Task)) error
+	Delete(id string) error
+}
+
+// Handler выполняет задачу своего типа. Возвращённый результат сериализуется
+// в JSON и сохраняется в поле result задачи. Ошибку, которую бесполезно
+// повторять, стоит обернуть в apperrors.Permanent, а просьбу повторить
+// позже — в apperrors.RetryAfter.
+type Handler func(ctx context.Context, task *model.Task) (any, error)
+
+type workerIDKey struct{}
+
+func WithWorkerID(ctx context.Context, workerID int) context.Context {
+	return context.WithValue(ctx, workerIDKey{}, workerID)
+}
+
+func WorkerIDFromContext(ctx context.Context) int {
+	id, _ := ctx.Value(workerIDKey{}).(int)
+	return id
 }
 
 type TaskService struct {
-	repo TaskRepo
+	repo       TaskRepo
+	clock      clock.Clock
+	metrics    *Metrics
+	events     *EventBus
+	retry      model.RetryPolicy
+	handlers   map[string]Handler
+	handlersMu sync.RWMutex
+	// running хранит функции отмены контекстов выполняющихся задач;
+	// mu также сериализует переходы статусов, которые конкурируют с отменой
+	running map[string]context.CancelCauseFunc
+	// waiters — каналы Wait, закрываемые при завершении задачи; под mu
+	waiters    map[string][]chan struct{}
+	onFinished []func(task model.Task)
+	mu         sync.Mutex
 }
 
-func NewTaskService(repo TaskRepo) *TaskService {
-	return &TaskService{
-		repo: repo,
+type TaskServiceOption func(ts *TaskService)
+
+// WithClock подменяет источник времени для отметок жизненного цикла задач.
+func WithClock(c clock.Clock) TaskServiceOption {
+	return func(ts *TaskService) {
+		ts.clock = c
 	}
 }
 
-func (ts *TaskService) Save(task *model.Task) error {
+// WithMetrics подключает метрики; по умолчанию они пишутся в собственный реестр сервиса.
+func WithMetrics(m *Metrics) TaskServiceOption {
+	return func(ts *TaskService) {
+		ts.metrics = m
+	}
+}
+
+// WithEvents подключает шину событий о переходах статусов задач.
+func WithEvents(bus *EventBus) TaskServiceOption {
+	return func(ts *TaskService) {
+		ts.events = bus
+	}
+}
+
+// WithRetryPolicy задаёт политику повторов для задач без собственной retry_policy.
+func WithRetryPolicy(p model.RetryPolicy) TaskServiceOption {
+	return func(ts *TaskService) {
+		ts.retry = p
+	}
+}
+
+func NewTaskService(repo TaskRepo, opts ...TaskServiceOption) *TaskService {
+	ts := &TaskService{
+		repo:     repo,
+		clock:    clock.Real{},
+		metrics:  NewMetrics(metrics.NewRegistry()),
+		events:   NewEventBus(DefaultEventHistory),
+		retry:    model.DefaultRetryPolicy(),
+		handlers: make(map[string]Handler),
+		running:  make(map[string]context.CancelCauseFunc),
+		waiters:  make(map[string][]chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(ts)
+	}
+
+	return ts
+}
+
+func (ts *TaskService) Now() time.Time {
+	return ts.clock.Now()
+}
+
+func (ts *TaskService) Metrics() *Metrics {
+	return ts.metrics
+}
+
+func (ts *TaskService) Events() *EventBus {
+	return ts.events
+}
+
+func (ts *TaskService) Register(taskType string, h Handler) {
+	ts.handlersMu.Lock()
+	defer ts.handlersMu.Unlock()
+
+	ts.handlers[taskType] = h
+}
+
+func (ts *TaskService) handler(taskType string) (Handler, error) {
+	ts.handlersMu.RLock()
+	defer ts.handlersMu.RUnlock()
+
+	h, ok := ts.handlers[taskType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown task type %q", apperrors.ErrInvalidData, taskType)
+	}
+
+	return h, nil
+}
+
+// Check проверяет новую задачу, не сохраняя её; если id не задан, он генерируется.
+func (ts *TaskService) Check(task *model.Task) error {
+	if task.ID == "" {
+		task.ID = newTaskID()
+	}
+
 	if err := model.ValidateTask(*task); err != nil {
 		return err
 	}
 
+	if _, err := ts.handler(task.Type); err != nil {
+		return err
+	}
+
+	if _, err := ts.repo.Get(task.ID); err == nil {
+		return fmt.Errorf("%w: task already exist", apperrors.ErrAlreadyExists)
+	}
+
+	return nil
+}
+
+// Save сохраняет новую задачу; если id не задан, он генерируется.
+func (ts *TaskService) Save(task *model.Task) error {
+	if err := ts.save(task); err != nil {
+		return err
+	}
+	ts.publish(task.ID, task.Status)
+
+	return nil
+}
+
+func (ts *TaskService) save(task *model.Task) error {
+	if err := ts.Check(task); err != nil {
+		return err
+	}
+
+	now := ts.clock.Now()
+	task.CreatedAt = now
 	task.Status = model.StatusQueued
+	task.QueuedAt = &now
+	if task.RunAt != nil && task.RunAt.After(now) {
+		task.Status = model.StatusScheduled
+		task.QueuedAt = nil
+	}
+
+	return ts.repo.Save(task)
+}
 
-	if err := ts.repo.Save(task); err != nil {
+// SaveAll сохраняет все задачи или ни одной: при ошибке уже сохранённые
+// молча удаляются — события о них публикуются только после сохранения всей
+// пачки, а rejected и метрика отказов остаются за контролем приёма.
+// Возвращает индекс задачи, на которой произошла ошибка.
+func (ts *TaskService) SaveAll(tasks []*model.Task) (int, error) {
+	for i, task := range tasks {
+		if err := ts.save(task); err != nil {
+			for _, saved := range tasks[:i] {
+				_ = ts.repo.Delete(saved.ID)
+			}
+			return i, err
+		}
+	}
+
+	for _, task := range tasks {
+		ts.publish(task.ID, task.Status)
+	}
+
+	return -1, nil
+}
+
+// Discard откатывает сохранение задачи, которую не удалось принять в очередь.
+// Подписчики, уже получившие событие о сохранении, получают rejected.
+func (ts *TaskService) Discard(id string) error {
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.repo.Delete(id); err != nil {
+		return err
+	}
+	ts.metrics.Rejected.Inc(task.Type)
+	ts.publishTask(task, model.StatusRejected)
+
+	return nil
+}
+
+// Restore возвращает задаче ранее прочитанное состояние — откат перехода,
+// после которого задачу не удалось принять в очередь.
+func (ts *TaskService) Restore(task *model.Task) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	snapshot := task.Clone()
+	if err := ts.repo.Update(task.ID, func(t *model.Task) {
+		*t = *snapshot
+	}); err != nil {
 		return err
 	}
+	ts.publish(task.ID, task.Status)
 
 	return nil
 }
@@ -53,43 +260,557 @@ func (ts *TaskService) IncAttempts(id string) error {
 	return ts.repo.IncAttempts(id)
 }
 
+func (ts *TaskService) ResetAttempts(id string) error {
+	return ts.repo.ResetAttempts(id)
+}
+
 func (ts *TaskService) List() []*model.Task {
 	return ts.repo.List()
 }
 
-func (ts *TaskService) HandleTask(ctx context.Context, task *model.Task) error {
-	if err := ts.UpdateStatus(task.ID, model.StatusRunning); err != nil {
+func (ts *TaskService) Query(q model.TaskQuery) (model.TaskPage, error) {
+	q, err := q.Normalize()
+	if err != nil {
+		return model.TaskPage{}, err
+	}
+
+	return ts.repo.Query(q)
+}
+
+// Recover возвращает задачи, которые нужно снова отдать пулу, в порядке
+// постановки в очередь до рестарта: по queued_at (created_at, если задача
+// ещё не ставилась), затем по ID.
+func (ts *TaskService) Recover() ([]*model.Task, error) {
+	var pending []*model.Task
+
+	// порядок берётся до markQueued, которая перезаписывает queued_at
+	tasks := ts.repo.List()
+	slices.SortFunc(tasks, func(a, b *model.Task) int {
+		if c := queuedSince(a).Compare(queuedSince(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	now := ts.clock.Now()
+	for _, task := range tasks {
+		switch {
+		case task.Status == model.StatusQueued,
+			task.Status == model.StatusScheduled:
+		case task.Status == model.StatusRunning,
+			task.Status == model.StatusFailed && task.CanRetry(now),
+			task.Status == model.StatusTimedOut && task.CanRetry(now):
+			if err := ts.repo.Update(task.ID, markQueued(now)); err != nil {
+				return nil, err
+			}
+			ts.publish(task.ID, model.StatusQueued)
+
+			var err error
+			if task, err = ts.repo.Get(task.ID); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+
+		pending = append(pending, task)
+	}
+
+	return pending, nil
+}
+
+func queuedSince(task *model.Task) time.Time {
+	if task.QueuedAt != nil {
+		return *task.QueuedAt
+	}
+	return task.CreatedAt
+}
+
+func (ts *TaskService) MarkQueued(id string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.repo.Get(id)
+	if err != nil {
 		return err
 	}
+	if task.Status == model.StatusCancelled {
+		return fmt.Errorf("%w: task %s", apperrors.ErrCancelled, id)
+	}
 
-	if err := ts.IncAttempts(task.ID); err != nil {
+	if err := ts.repo.Update(id, markQueued(ts.clock.Now())); err != nil {
 		return err
 	}
+	ts.publish(id, model.StatusQueued)
 
-	if err := processing(ctx); err != nil {
-		if updateErr := ts.UpdateStatus(task.ID, model.StatusFailed); updateErr != nil {
-			return updateErr
-		}
+	return nil
+}
+
+// Revive возвращает окончательно упавшую задачу в очередь как новую:
+// обнуляет попытки и сбрасывает итог прошлого запуска (finished_at,
+// last_error, статус webhook). History и deliveries сохраняются.
+func (ts *TaskService) Revive(id string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.repo.Get(id)
+	if err != nil {
 		return err
 	}
+	if task.Status == model.StatusCancelled {
+		return fmt.Errorf("%w: task %s", apperrors.ErrCancelled, id)
+	}
 
-	if err := ts.UpdateStatus(task.ID, model.StatusDone); err != nil {
+	queue := markQueued(ts.clock.Now())
+	if err := ts.repo.Update(id, func(t *model.Task) {
+		queue(t)
+		t.Attempts = 0
+		t.FinishedAt = nil
+		t.LastError = ""
+		t.FailedPermanently = false
+		t.CallbackStatus = ""
+	}); err != nil {
 		return err
 	}
+	ts.publish(id, model.StatusQueued)
 
 	return nil
 }
 
-func processing(ctx context.Context) error {
+func markQueued(now time.Time) func(t *model.Task) {
+	return func(t *model.Task) {
+		t.Status = model.StatusQueued
+		t.QueuedAt = &now
+		t.NextRetryAt = nil
+	}
+}
+
+// ScheduleRetry рассчитывает по политике повторов задачи паузу перед
+// следующей попыткой и отмечает, когда задача вернётся в очередь.
+// Вызывается один раз после каждой упавшей попытки.
+func (ts *TaskService) ScheduleRetry(id string) (time.Duration, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	if task.Status == model.StatusCancelled {
+		return 0, fmt.Errorf("%w: task %s", apperrors.ErrCancelled, id)
+	}
+
+	policy := ts.retry
+	if task.RetryPolicy != nil {
+		policy = *task.RetryPolicy
+	}
+	delay := policy.Delay(task.Attempts, time.Duration(task.RetryDelay), mathrand.Int63n)
+
+	now := ts.clock.Now()
+	// next_retry_at до ScheduleRetry выставляет только finish по
+	// apperrors.RetryAfter — подсказка обработчика важнее политики
+	if task.NextRetryAt != nil {
+		delay = max(task.NextRetryAt.Sub(now), 0)
+	}
+
+	retryAt := now.Add(delay)
+	if err := ts.repo.Update(id, func(t *model.Task) {
+		t.NextRetryAt = &retryAt
+		t.RetryDelay = model.Duration(delay)
+	}); err != nil {
+		return 0, err
+	}
+	ts.publish(id, model.StatusRetrying)
+
+	return delay, nil
+}
+
+// RescheduleRetry переносит ожидающий повтор задачи на время at.
+func (ts *TaskService) RescheduleRetry(id string, at time.Time) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		return err
+	}
+	if task.NextRetryAt == nil ||
+		task.Status != model.StatusFailed && task.Status != model.StatusTimedOut {
+		return fmt.Errorf("%w: task %s is not waiting for retry", apperrors.ErrConflict, id)
+	}
+
+	if err := ts.repo.Update(id, func(t *model.Task) {
+		t.NextRetryAt = &at
+	}); err != nil {
+		return err
+	}
+	ts.publish(id, model.StatusRetrying)
+
+	return nil
+}
+
+func (ts *TaskService) Cancel(id string) (*model.Task, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := ts.clock.Now()
+	if task.Finished(now) {
+		return nil, fmt.Errorf("%w: task is already %s", apperrors.ErrConflict, task.Status)
+	}
+
+	if err := ts.repo.Update(id, func(t *model.Task) {
+		t.Status = model.StatusCancelled
+		t.FinishedAt = &now
+		t.NextRetryAt = nil
+	}); err != nil {
+		return nil, err
+	}
+	ts.publish(id, model.StatusCancelled)
+	ts.settle(id)
+
+	if cancel, ok := ts.running[id]; ok {
+		cancel(apperrors.ErrCancelled)
+	}
+
+	return ts.repo.Get(id)
+}
+
+func (ts *TaskService) Expire(id string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		return err
+	}
+	if task.Status == model.StatusCancelled {
+		return fmt.Errorf("%w: task %s", apperrors.ErrCancelled, id)
+	}
+
+	now := ts.clock.Now()
+	if err := ts.repo.Update(id, func(t *model.Task) {
+		t.Status = model.StatusTimedOut
+		t.LastError = fmt.Errorf("%w: deadline exceeded", apperrors.ErrTimedOut).Error()
+		t.FinishedAt = &now
+		t.NextRetryAt = nil
+	}); err != nil {
+		return err
+	}
+	ts.publish(id, model.StatusTimedOut)
+	ts.settle(id)
+
+	return nil
+}
+
+// HandleTask выполняет попытку задачи. Обработчик получает состояние задачи
+// из репозитория на момент старта попытки, а не переданный указатель.
+func (ts *TaskService) HandleTask(ctx context.Context, task *model.Task) error {
+	ctx, cancel, current, err := ts.start(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	defer cancel(nil)
+
+	var result any
+	h, err := ts.handler(task.Type)
+	if err == nil {
+		startedAt := ts.clock.Now()
+		result, err = h(ctx, current)
+		ts.metrics.HandlerDuration.Observe(ts.clock.Now().Sub(startedAt).Seconds(), task.Type)
+	}
+
+	return ts.finish(ctx, task.ID, task.Type, result, err)
+}
+
+func (ts *TaskService) start(ctx context.Context, id string) (context.Context, context.CancelCauseFunc, *model.Task, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if task.Status == model.StatusCancelled {
+		return nil, nil, nil, fmt.Errorf("%w: task %s", apperrors.ErrCancelled, id)
+	}
+	now := ts.clock.Now()
+	if task.DeadlineExceeded(now) {
+		deadlineErr := fmt.Errorf("%w: task %s deadline exceeded", apperrors.ErrTimedOut, id)
+		if err := ts.repo.Update(id, func(t *model.Task) {
+			t.Status = model.StatusTimedOut
+			t.LastError = deadlineErr.Error()
+			t.FinishedAt = &now
+		}); err != nil {
+			return nil, nil, nil, err
+		}
+		ts.publish(id, model.StatusTimedOut)
+		ts.settle(id)
+		return nil, nil, nil, deadlineErr
+	}
+
+	if task.QueuedAt != nil {
+		ts.metrics.QueueWait.Observe(now.Sub(*task.QueuedAt).Seconds(), task.Type)
+	}
+
+	workerID := WorkerIDFromContext(ctx)
+	if err := ts.repo.Update(id, func(t *model.Task) {
+		t.Status = model.StatusRunning
+		t.Attempts++
+		t.StartedAt = &now
+		t.FinishedAt = nil
+		t.NextRetryAt = nil
+		t.FailedPermanently = false
+		t.History = append(t.History, model.Attempt{
+			Number:    t.Attempts,
+			WorkerID:  workerID,
+			StartedAt: now,
+		})
+	}); err != nil {
+		return nil, nil, nil, err
+	}
+	ts.publish(id, model.StatusRunning)
+
+	current, err := ts.repo.Get(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	taskCtx, cancel := context.WithCancelCause(ctx)
+	ts.running[id] = cancel
+
+	return taskCtx, cancel, current, nil
+}
+
+func (ts *TaskService) finish(ctx context.Context, id, taskType string, result any, handlerErr error) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	delete(ts.running, id)
+
+	var (
+		status = model.StatusDone
+		outErr error
+		raw    json.RawMessage
+	)
+
+	switch cause := context.Cause(ctx); {
+	// статус cancelled уже выставлен в Cancel, результат обработчика не важен
+	case errors.Is(cause, apperrors.ErrCancelled):
+		status = model.StatusCancelled
+		outErr = fmt.Errorf("%w: task %s", apperrors.ErrCancelled, id)
+	// таймаут попытки/дедлайн задаются производными контекстами в worker pool
+	case handlerErr != nil && errors.Is(cause, apperrors.ErrTimedOut):
+		status = model.StatusTimedOut
+		outErr = cause
+	// отмена родительского контекста — остановка сервиса: попытка не
+	// засчитывается, задача возвращается в queued и поднимется при Recover
+	case handlerErr != nil && errors.Is(cause, context.Canceled):
+		status = model.StatusQueued
+		outErr = fmt.Errorf("%w: task %s: %w", apperrors.ErrInterrupted, id, handlerErr)
+	case handlerErr != nil:
+		status = model.StatusFailed
+		outErr = handlerErr
+	case result != nil:
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			status = model.StatusFailed
+			outErr = fmt.Errorf("encode result: %w", err)
+			break
+		}
+		raw = encoded
+	}
+
+	finishedAt := ts.clock.Now()
+	if err := ts.repo.Update(id, func(t *model.Task) {
+		if status == model.StatusQueued {
+			t.Attempts--
+			t.History = t.History[:len(t.History)-1]
+			markQueued(finishedAt)(t)
+			return
+		}
+
+		t.Status = status
+		t.FinishedAt = &finishedAt
+		if status == model.StatusFailed {
+			t.FailedPermanently = apperrors.IsPermanent(outErr)
+			if d, ok := apperrors.RetryAfterDelay(outErr); ok {
+				retryAt := finishedAt.Add(d)
+				t.NextRetryAt = &retryAt
+			}
+		}
+		if n := len(t.History); n > 0 {
+			t.History[n-1].FinishedAt = &finishedAt
+			if outErr != nil {
+				t.History[n-1].Error = outErr.Error()
+			}
+		}
+		if outErr != nil {
+			t.LastError = outErr.Error()
+			return
+		}
+		t.Result = raw
+	}); err != nil {
+		return err
+	}
+
+	switch status {
+	case model.StatusDone:
+		ts.metrics.Succeeded.Inc(taskType)
+	case model.StatusFailed, model.StatusTimedOut:
+		ts.metrics.Failed.Inc(taskType)
+	}
+	// об отмене уже сообщил Cancel
+	if status != model.StatusCancelled {
+		ts.publish(id, status)
+	}
+	ts.settle(id)
+
+	return outErr
+}
+
+// Wait блокируется, пока задача не завершится окончательно (см. Task.Finished)
+// или не истечёт ctx, и возвращает её. Ожидание не занимает воркер: Wait
+// будят переходы статусов в HandleTask, Cancel и Expire. При истечении ctx
+// задача возвращается в текущем состоянии вместе с ошибкой контекста.
+func (ts *TaskService) Wait(ctx context.Context, id string) (*model.Task, error) {
+	ts.mu.Lock()
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		ts.mu.Unlock()
+		return nil, err
+	}
+	if task.Finished(ts.clock.Now()) {
+		ts.mu.Unlock()
+		return task, nil
+	}
+	done := make(chan struct{})
+	ts.waiters[id] = append(ts.waiters[id], done)
+	ts.mu.Unlock()
+
 	select {
+	case <-done:
 	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(100 + time.Duration(rand.Intn(4)*100)*time.Millisecond):
+		ts.mu.Lock()
+		ts.waiters[id] = slices.DeleteFunc(ts.waiters[id], func(ch chan struct{}) bool {
+			return ch == done
+		})
+		if len(ts.waiters[id]) == 0 {
+			delete(ts.waiters, id)
+		}
+		ts.mu.Unlock()
 	}
 
-	if rand.Intn(100) < 20 {
-		return fmt.Errorf("simulated processing failed")
+	task, err = ts.repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil && !task.Finished(ts.clock.Now()) {
+		return task, err
 	}
 
-	return nil
+	return task, nil
+}
+
+// OnFinished регистрирует fn, который получает копию задачи после её
+// окончательного завершения. fn вызывается под блокировкой сервиса и не
+// должен блокироваться.
+func (ts *TaskService) OnFinished(fn func(task model.Task)) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.onFinished = append(ts.onFinished, fn)
+}
+
+// settle вызывается под ts.mu после перехода, который может завершить
+// задачу: будит Wait, отмечает webhook к доставке и уведомляет OnFinished.
+func (ts *TaskService) settle(id string) {
+	task, err := ts.repo.Get(id)
+	if err != nil || !task.Finished(ts.clock.Now()) {
+		return
+	}
+
+	for _, ch := range ts.waiters[id] {
+		close(ch)
+	}
+	delete(ts.waiters, id)
+
+	if task.CallbackURL != "" {
+		_ = ts.repo.Update(id, func(t *model.Task) {
+			t.CallbackStatus = model.CallbackPending
+		})
+	}
+
+	for _, fn := range ts.onFinished {
+		fn(*task)
+	}
+}
+
+// RecordDelivery сохраняет попытку доставки webhook и её итог.
+func (ts *TaskService) RecordDelivery(id string, d model.Delivery, status model.CallbackStatus) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.repo.Update(id, func(t *model.Task) {
+		t.Deliveries = append(t.Deliveries, d)
+		t.CallbackStatus = status
+	})
+}
+
+// publish сообщает подписчикам о переходе задачи в status; вызывается после
+// успешного обновления репозитория.
+func (ts *TaskService) publish(id string, status model.TaskStatus) {
+	task, err := ts.repo.Get(id)
+	if err != nil {
+		return
+	}
+	ts.publishTask(task, status)
+}
+
+func (ts *TaskService) publishTask(task *model.Task, status model.TaskStatus) {
+	ev := model.TaskEvent{
+		TaskID:   task.ID,
+		Type:     task.Type,
+		Status:   status,
+		Attempts: task.Attempts,
+		Time:     ts.clock.Now(),
+	}
+	switch status {
+	case model.StatusFailed, model.StatusTimedOut, model.StatusRetrying:
+		ev.Error = task.LastError
+	}
+	if status == model.StatusRetrying {
+		ev.NextRetryAt = task.NextRetryAt
+	}
+
+	ts.events.Publish(ev)
+}
+
+// newTaskID возвращает случайный UUID версии 4.
+func newTaskID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func Simulation(ctx context.Context, _ *model.Task) (any, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(100 + time.Duration(mathrand.Intn(4)*100)*time.Millisecond):
+	}
+
+	if mathrand.Intn(100) < 20 {
+		return nil, fmt.Errorf("simulated processing failed")
+	}
+
+	return nil, nil
 }
@@ -2,17 +2,23 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/folivorra/task_queue/internal/model"
 	"github.com/folivorra/task_queue/internal/repository/inmemory"
 	"github.com/folivorra/task_queue/internal/usecase"
+	"github.com/folivorra/task_queue/pkg/apperrors"
+	"github.com/folivorra/task_queue/pkg/clock"
 )
 
 func TestTaskService_HandleTask(t *testing.T) {
 	repo := inmemory.NewTaskInMemoryRepo()
 	service := usecase.NewTaskService(repo)
-	task := &model.Task{ID: "t1", MaxRetries: 3}
+	service.Register("simulation", usecase.Simulation)
+	task := &model.Task{ID: "t1", Type: "simulation", MaxRetries: 3}
 
 	if err := service.Save(task); err != nil {
 		t.Fatalf("save failed: %v", err)
@@ -20,7 +26,507 @@ func TestTaskService_HandleTask(t *testing.T) {
 
 	ctx := context.Background()
 	err := service.HandleTask(ctx, task)
+	task, _ = repo.Get(task.ID)
 	if err != nil && task.Status != model.StatusFailed {
 		t.Errorf("expected failed task to be marked failed, got status %s", task.Status)
 	}
 }
+
+func TestTaskService_HandleTaskDispatchesByType(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	var called []string
+	service.Register("email", func(ctx context.Context, task *model.Task) (any, error) {
+		called = append(called, "email:"+task.ID)
+		return nil, nil
+	})
+	service.Register("cleanup", func(ctx context.Context, task *model.Task) (any, error) {
+		called = append(called, "cleanup:"+task.ID)
+		return nil, errors.New("cleanup failed")
+	})
+
+	emailTask := &model.Task{ID: "t1", Type: "email"}
+	cleanupTask := &model.Task{ID: "t2", Type: "cleanup"}
+	for _, task := range []*model.Task{emailTask, cleanupTask} {
+		if err := service.Save(task); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+
+	if err := service.HandleTask(context.Background(), emailTask); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	emailTask, _ = repo.Get(emailTask.ID)
+	if emailTask.Status != model.StatusDone {
+		t.Errorf("status = %s, want %s", emailTask.Status, model.StatusDone)
+	}
+
+	if err := service.HandleTask(context.Background(), cleanupTask); err == nil {
+		t.Fatal("expected handler error")
+	}
+	cleanupTask, _ = repo.Get(cleanupTask.ID)
+	if cleanupTask.Status != model.StatusFailed {
+		t.Errorf("status = %s, want %s", cleanupTask.Status, model.StatusFailed)
+	}
+
+	if len(called) != 2 || called[0] != "email:t1" || called[1] != "cleanup:t2" {
+		t.Errorf("unexpected handler calls: %v", called)
+	}
+}
+
+func TestTaskService_SaveRejectsUnknownType(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	err := service.Save(&model.Task{ID: "t1", Type: "unknown"})
+	if !errors.Is(err, apperrors.ErrInvalidData) {
+		t.Fatalf("expected ErrInvalidData, got %v", err)
+	}
+
+	if _, err := repo.Get("t1"); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("task with unknown type must not be saved, got %v", err)
+	}
+}
+
+func TestTaskService_Recover(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	tasks := []*model.Task{
+		{ID: "queued", Status: model.StatusQueued},
+		{ID: "running", Status: model.StatusRunning, Attempts: 1, MaxRetries: 3},
+		{ID: "retry", Status: model.StatusFailed, Attempts: 1, MaxRetries: 3},
+		{ID: "failed", Status: model.StatusFailed, Attempts: 3, MaxRetries: 3},
+		{ID: "done", Status: model.StatusDone, Attempts: 1},
+	}
+	for _, task := range tasks {
+		_ = repo.Save(task)
+	}
+
+	pending, err := service.Recover()
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, task := range pending {
+		got[task.ID] = true
+		if task.Status != model.StatusQueued {
+			t.Errorf("task %s status = %s, want %s", task.ID, task.Status, model.StatusQueued)
+		}
+	}
+
+	if len(got) != 3 || !got["queued"] || !got["running"] || !got["retry"] {
+		t.Errorf("unexpected recovered tasks: %v", got)
+	}
+}
+
+func TestTaskService_RecoverKeepsQueueOrder(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		ts := base.Add(d)
+		return &ts
+	}
+	tasks := []*model.Task{
+		{ID: "e", Status: model.StatusQueued, CreatedAt: base, QueuedAt: at(3 * time.Second)},
+		{ID: "b", Status: model.StatusRunning, MaxRetries: 3, CreatedAt: base, QueuedAt: at(time.Second)},
+		{ID: "d", Status: model.StatusQueued, CreatedAt: base, QueuedAt: at(2 * time.Second)},
+		{ID: "c", Status: model.StatusQueued, CreatedAt: base, QueuedAt: at(2 * time.Second)},
+		{ID: "a", Status: model.StatusQueued, CreatedAt: base.Add(500 * time.Millisecond)},
+	}
+	for _, task := range tasks {
+		_ = repo.Save(task)
+	}
+
+	pending, err := service.Recover()
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+
+	var got []string
+	for _, task := range pending {
+		got = append(got, task.ID)
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !slices.Equal(got, want) {
+		t.Errorf("recovered order = %v, want %v", got, want)
+	}
+}
+
+func TestTaskService_CancelQueuedTaskIsDropped(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	called := false
+	service.Register("email", func(ctx context.Context, task *model.Task) (any, error) {
+		called = true
+		return nil, nil
+	})
+
+	task := &model.Task{ID: "t1", Type: "email"}
+	_ = service.Save(task)
+
+	if _, err := service.Cancel("t1"); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+
+	err := service.HandleTask(context.Background(), task)
+	if !errors.Is(err, apperrors.ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	task, _ = repo.Get(task.ID)
+	if called || task.Status != model.StatusCancelled {
+		t.Errorf("cancelled task must not run, called=%v status=%s", called, task.Status)
+	}
+
+	if err := service.MarkQueued("t1"); !errors.Is(err, apperrors.ErrCancelled) {
+		t.Errorf("cancelled task must not be queued again, got %v", err)
+	}
+}
+
+func TestTaskService_CancelRunningTask(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	started := make(chan struct{})
+	service.Register("long", func(ctx context.Context, task *model.Task) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	task := &model.Task{ID: "t1", Type: "long"}
+	_ = service.Save(task)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- service.HandleTask(context.Background(), task)
+	}()
+
+	<-started
+	if _, err := service.Cancel("t1"); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+
+	if err := <-done; !errors.Is(err, apperrors.ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	task, _ = repo.Get(task.ID)
+	if task.Status != model.StatusCancelled {
+		t.Errorf("status = %s, want %s", task.Status, model.StatusCancelled)
+	}
+
+	if _, err := service.Cancel("t1"); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("expected ErrConflict on second cancel, got %v", err)
+	}
+}
+
+func TestTaskService_HandleTaskStoresResultAndHistory(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	calls := 0
+	service.Register("resize", func(ctx context.Context, task *model.Task) (any, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("storage unavailable")
+		}
+		return map[string]string{"url": "https://cdn/img.png"}, nil
+	})
+
+	task := &model.Task{ID: "t1", Type: "resize", MaxRetries: 2}
+	_ = service.Save(task)
+
+	ctx := usecase.WithWorkerID(context.Background(), 7)
+	if err := service.HandleTask(ctx, task); err == nil {
+		t.Fatal("expected first attempt to fail")
+	}
+	task, _ = repo.Get(task.ID)
+	if task.LastError != "storage unavailable" {
+		t.Errorf("last_error = %q", task.LastError)
+	}
+
+	if err := service.HandleTask(ctx, task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	task, _ = repo.Get(task.ID)
+	if string(task.Result) != `{"url":"https://cdn/img.png"}` {
+		t.Errorf("result = %s", task.Result)
+	}
+	if len(task.History) != 2 {
+		t.Fatalf("expected 2 attempts in history, got %d", len(task.History))
+	}
+
+	first, second := task.History[0], task.History[1]
+	if first.Number != 1 || first.WorkerID != 7 || first.Error != "storage unavailable" || first.FinishedAt == nil {
+		t.Errorf("unexpected first attempt: %+v", first)
+	}
+	if second.Number != 2 || second.Error != "" || second.FinishedAt.Before(second.StartedAt) {
+		t.Errorf("unexpected second attempt: %+v", second)
+	}
+}
+
+func TestTaskService_LifecycleTimestamps(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) { now = now.Add(d) }
+
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo, usecase.WithClock(clock.Func(func() time.Time { return now })))
+
+	fail := true
+	service.Register("flaky", func(ctx context.Context, task *model.Task) (any, error) {
+		advance(2 * time.Second)
+		if fail {
+			fail = false
+			return nil, errors.New("boom")
+		}
+		return nil, nil
+	})
+
+	task := &model.Task{ID: "t1", Type: "flaky", MaxRetries: 2,
+		RetryPolicy: &model.RetryPolicy{Strategy: model.RetryFixed, Base: model.Duration(time.Second)}}
+	_ = service.Save(task)
+	task, _ = repo.Get(task.ID)
+
+	created := now
+	if !task.CreatedAt.Equal(created) || task.QueuedAt == nil || !task.QueuedAt.Equal(created) {
+		t.Fatalf("unexpected timestamps after save: created=%v queued=%v", task.CreatedAt, task.QueuedAt)
+	}
+
+	advance(5 * time.Second)
+	_ = service.HandleTask(context.Background(), task)
+	task, _ = repo.Get(task.ID)
+
+	if !task.StartedAt.Equal(created.Add(5*time.Second)) || !task.FinishedAt.Equal(created.Add(7*time.Second)) {
+		t.Errorf("unexpected attempt timestamps: started=%v finished=%v", task.StartedAt, task.FinishedAt)
+	}
+
+	if _, err := service.ScheduleRetry(task.ID); err != nil {
+		t.Fatalf("schedule retry failed: %v", err)
+	}
+	task, _ = repo.Get(task.ID)
+	if task.NextRetryAt == nil || !task.NextRetryAt.Equal(created.Add(8*time.Second)) {
+		t.Errorf("unexpected next_retry_at: %v", task.NextRetryAt)
+	}
+
+	advance(time.Second)
+	_ = service.MarkQueued(task.ID)
+	task, _ = repo.Get(task.ID)
+	if task.NextRetryAt != nil || !task.QueuedAt.Equal(created.Add(8*time.Second)) {
+		t.Errorf("requeue must reset next_retry_at and queued_at: next=%v queued=%v", task.NextRetryAt, task.QueuedAt)
+	}
+
+	if err := service.HandleTask(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	task, _ = repo.Get(task.ID)
+	if !task.StartedAt.Equal(created.Add(8*time.Second)) || !task.FinishedAt.Equal(created.Add(10*time.Second)) {
+		t.Errorf("unexpected attempt timestamps: started=%v finished=%v", task.StartedAt, task.FinishedAt)
+	}
+	if !task.CreatedAt.Equal(created) {
+		t.Errorf("created_at must not change, got %v", task.CreatedAt)
+	}
+}
+
+func TestTaskService_WaitReturnsOnlyFinishedTasks(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	attempts := 0
+	service.Register("flaky", func(ctx context.Context, task *model.Task) (any, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("boom")
+		}
+		return nil, nil
+	})
+
+	task := &model.Task{ID: "t1", Type: "flaky", MaxRetries: 2}
+	_ = service.Save(task)
+
+	waited := make(chan *model.Task, 1)
+	go func() {
+		task, _ := service.Wait(context.Background(), "t1")
+		waited <- task
+	}()
+
+	// упавшая попытка с правом на повтор ещё не завершает задачу
+	_ = service.HandleTask(context.Background(), task)
+	select {
+	case <-waited:
+		t.Fatal("wait must not return while the task can be retried")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_ = service.HandleTask(context.Background(), task)
+	select {
+	case got := <-waited:
+		if got.Status != model.StatusDone {
+			t.Errorf("status = %s, want %s", got.Status, model.StatusDone)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait must return once the task is done")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err := service.Wait(ctx, "t1"); err != nil || got.Status != model.StatusDone {
+		t.Errorf("finished task must be returned immediately, got %v", err)
+	}
+}
+
+func TestRetryPolicy_DelayNeverExceedsMax(t *testing.T) {
+	limit := 5 * time.Second
+	// наибольшая возможная случайная добавка
+	rnd := func(n int64) int64 { return n - 1 }
+
+	for _, strategy := range []model.RetryStrategy{model.RetryFixed, model.RetryLinear, model.RetryExponential, model.RetryDecorrelated} {
+		policy := model.RetryPolicy{Strategy: strategy, Base: model.Duration(time.Second), Max: model.Duration(limit), Jitter: 1}
+
+		prev := time.Duration(0)
+		for attempt := 1; attempt <= 100; attempt++ {
+			delay := policy.Delay(attempt, prev, rnd)
+			if delay > limit {
+				t.Fatalf("%s attempt %d: delay %s exceeds max %s", strategy, attempt, delay, limit)
+			}
+			prev = delay
+		}
+	}
+}
+
+func TestTaskService_ScheduleRetryPolicies(t *testing.T) {
+	sec := model.Duration(time.Second)
+
+	tests := []struct {
+		name   string
+		policy *model.RetryPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "fixed",
+			policy: &model.RetryPolicy{Strategy: model.RetryFixed, Base: sec},
+			want:   []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:   "linear",
+			policy: &model.RetryPolicy{Strategy: model.RetryLinear, Base: sec, Max: model.Duration(time.Minute), Multiplier: 2},
+			want:   []time.Duration{time.Second, 3 * time.Second, 5 * time.Second},
+		},
+		{
+			name:   "exponential capped",
+			policy: &model.RetryPolicy{Strategy: model.RetryExponential, Base: sec, Max: model.Duration(3 * time.Second)},
+			want:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := inmemory.NewTaskInMemoryRepo()
+			service := usecase.NewTaskService(repo)
+			service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+				return nil, nil
+			})
+
+			_ = service.Save(&model.Task{ID: "t1", Type: "noop", MaxRetries: 10, RetryPolicy: tt.policy})
+
+			for i, want := range tt.want {
+				_ = repo.Update("t1", func(task *model.Task) { task.Attempts = i + 1 })
+				_ = service.MarkQueued("t1")
+				got, err := service.ScheduleRetry("t1")
+				if err != nil {
+					t.Fatalf("schedule retry failed: %v", err)
+				}
+				task, _ := repo.Get("t1")
+				if got != want || time.Duration(task.RetryDelay) != want {
+					t.Errorf("attempt %d: delay = %s, want %s", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("decorrelated jitter and server default", func(t *testing.T) {
+		policy := model.RetryPolicy{Strategy: model.RetryDecorrelated, Base: sec, Max: model.Duration(10 * time.Second)}
+		repo := inmemory.NewTaskInMemoryRepo()
+		service := usecase.NewTaskService(repo, usecase.WithRetryPolicy(policy))
+		service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+			return nil, nil
+		})
+
+		_ = service.Save(&model.Task{ID: "t1", Type: "noop", MaxRetries: 10})
+
+		prev := time.Second
+		for i := 1; i <= 20; i++ {
+			_ = repo.Update("t1", func(task *model.Task) { task.Attempts = i })
+			_ = service.MarkQueued("t1")
+			got, _ := service.ScheduleRetry("t1")
+			task, _ := repo.Get("t1")
+			if got < time.Second || got > min(3*prev, 10*time.Second) {
+				t.Fatalf("attempt %d: delay %s outside [1s, %s]", i, got, min(3*prev, 10*time.Second))
+			}
+			if task.NextRetryAt == nil || time.Duration(task.RetryDelay) != got {
+				t.Fatalf("attempt %d: next_retry_at and retry_delay must be set", i)
+			}
+			prev = got
+		}
+	})
+}
+
+func TestTaskService_SaveValidatesRetryPolicy(t *testing.T) {
+	service := usecase.NewTaskService(inmemory.NewTaskInMemoryRepo())
+	service.Register("noop", func(ctx context.Context, task *model.Task) (any, error) {
+		return nil, nil
+	})
+
+	for _, policy := range []model.RetryPolicy{
+		{Strategy: "random"},
+		{Strategy: model.RetryFixed, Base: model.Duration(time.Minute), Max: model.Duration(time.Second)},
+		{Strategy: model.RetryExponential, Multiplier: 0.5},
+		{Strategy: model.RetryLinear, Jitter: 2},
+	} {
+		err := service.Save(&model.Task{ID: "t1", Type: "noop", RetryPolicy: &policy})
+		if !errors.Is(err, apperrors.ErrInvalidData) {
+			t.Errorf("policy %+v: expected ErrInvalidData, got %v", policy, err)
+		}
+	}
+}
+
+func TestTaskService_InterruptedAttemptIsNotCounted(t *testing.T) {
+	repo := inmemory.NewTaskInMemoryRepo()
+	service := usecase.NewTaskService(repo)
+
+	started := make(chan struct{})
+	service.Register("long", func(ctx context.Context, task *model.Task) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	task := &model.Task{ID: "t1", Type: "long", MaxRetries: 1}
+	_ = service.Save(task)
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- service.HandleTask(ctx, task)
+	}()
+
+	<-started
+	shutdown()
+
+	if err := <-done; !errors.Is(err, apperrors.ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+	if task.Status != model.StatusQueued || task.Attempts != 0 || len(task.History) != 0 {
+		t.Errorf("interrupted task must be requeued without consuming an attempt, got %s attempts=%d history=%d",
+			task.Status, task.Attempts, len(task.History))
+	}
+
+	pending, _ := service.Recover()
+	if len(pending) != 1 || pending[0].ID != "t1" {
+		t.Errorf("interrupted task must be recovered, got %v", pending)
+	}
+}
@@ -1,9 +1,67 @@
 package apperrors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("already exists")
 	ErrInvalidData   = errors.New("invalid data")
+	ErrConflict      = errors.New("conflict")
+	ErrCancelled     = errors.New("cancelled")
+	ErrTimedOut      = errors.New("timed out")
+	ErrOverloaded    = errors.New("overloaded")
+	ErrUnavailable   = errors.New("unavailable")
+	ErrUnprocessable = errors.New("unprocessable")
+	ErrInterrupted   = errors.New("interrupted")
 )
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку обработчика как окончательную: задача падает
+// сразу, без оставшихся повторов.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter просит повторить задачу не раньше чем через d вместо паузы
+// по политике повторов. Число попыток по-прежнему ограничено max_retries.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, delay: d}
+}
+
+func RetryAfterDelay(err error) (time.Duration, bool) {
+	var re *retryAfterError
+	if !errors.As(err, &re) {
+		return 0, false
+	}
+	return re.delay, true
+}
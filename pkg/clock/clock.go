@@ -0,0 +1,22 @@
+package clock
+
+import "time"
+
+// Clock — источник текущего времени, подменяемый в тестах.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real возвращает системное время.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Func позволяет использовать функцию как Clock.
+type Func func() time.Time
+
+func (f Func) Now() time.Time {
+	return f()
+}
@@ -0,0 +1,73 @@
+package cron_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/folivorra/task_queue/pkg/cron"
+)
+
+func TestParse_Next(t *testing.T) {
+	base := time.Date(2025, time.March, 14, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.March, 14, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, time.March, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, time.March, 15, 9, 0, 0, 0, time.UTC)},
+		{"30 8-18/2 * * *", time.Date(2025, time.March, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon", time.Date(2025, time.March, 17, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2025, time.March, 16, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2025, time.March, 21, 0, 0, 0, 0, time.UTC)},
+		{"5,10 0 * jan,feb *", time.Date(2026, time.January, 1, 0, 5, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2025, time.March, 14, 10, 19, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := cron.Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+
+			if got := s.Next(base); !got.Equal(tt.want) {
+				t.Errorf("next = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"@every -1s",
+		"@every soon",
+	} {
+		if _, err := cron.Parse(spec); !errors.Is(err, cron.ErrInvalidSpec) {
+			t.Errorf("spec %q: expected ErrInvalidSpec, got %v", spec, err)
+		}
+	}
+}
+
+func TestParse_NeverFires(t *testing.T) {
+	s, err := cron.Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("expected zero time, got %s", got)
+	}
+}
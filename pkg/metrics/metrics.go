@@ -0,0 +1,328 @@
+// Package metrics — минимальная реализация метрик в текстовом формате
+// Prometheus (exposition format 0.0.4) без внешних зависимостей.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo выводит все метрики в порядке регистрации.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// series — набор значений метрики, разложенный по значениям меток.
+type series[T any] struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	mu     sync.Mutex
+	values map[string]*entry[T]
+	init   func() T
+}
+
+type entry[T any] struct {
+	labels []string
+	value  T
+}
+
+func newSeries[T any](name, help, typ string, labels []string, init func() T) *series[T] {
+	return &series[T]{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		values: make(map[string]*entry[T]),
+		init:   init,
+	}
+}
+
+// with возвращает значение для набора меток; вызывается под s.mu.
+func (s *series[T]) with(labelValues []string) *entry[T] {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", s.name, len(s.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	e, ok := s.values[key]
+	if !ok {
+		e = &entry[T]{labels: append([]string(nil), labelValues...), value: s.init()}
+		s.values[key] = e
+	}
+
+	return e
+}
+
+func (s *series[T]) write(w *bufio.Writer, sample func(w *bufio.Writer, labelValues []string, v T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", s.name, escapeHelp(s.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", s.name, s.typ)
+
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		e := s.values[k]
+		sample(w, e.labels, e.value)
+	}
+}
+
+type CounterVec struct {
+	s *series[float64]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{s: newSeries(name, help, "counter", labels, func() float64 { return 0 })}
+	r.register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+
+	c.s.with(labelValues).value += v
+}
+
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+
+	return c.s.with(labelValues).value
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.s.write(w, func(w *bufio.Writer, labelValues []string, v float64) {
+		fmt.Fprintf(w, "%s%s %s\n", c.s.name, formatLabels(c.s.labels, labelValues), formatFloat(v))
+	})
+}
+
+type Gauge struct {
+	s *series[float64]
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{s: newSeries(name, help, "gauge", nil, func() float64 { return 0 })}
+	g.s.with(nil)
+	r.register(g)
+	return g
+}
+
+func (g *Gauge) Add(v float64) {
+	g.s.mu.Lock()
+	defer g.s.mu.Unlock()
+
+	g.s.with(nil).value += v
+}
+
+func (g *Gauge) Inc() { g.Add(1) }
+
+func (g *Gauge) Dec() { g.Add(-1) }
+
+func (g *Gauge) Set(v float64) {
+	g.s.mu.Lock()
+	defer g.s.mu.Unlock()
+
+	g.s.with(nil).value = v
+}
+
+func (g *Gauge) Value() float64 {
+	g.s.mu.Lock()
+	defer g.s.mu.Unlock()
+
+	return g.s.with(nil).value
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.s.write(w, func(w *bufio.Writer, _ []string, v float64) {
+		fmt.Fprintf(w, "%s %s\n", g.s.name, formatFloat(v))
+	})
+}
+
+// GaugeFunc считывает значение в момент сбора метрик, например длину очереди.
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", g.name, escapeHelp(g.help))
+	fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type HistogramVec struct {
+	s       *series[*histogram]
+	buckets []float64
+}
+
+// NewHistogramVec создаёт гистограмму с верхними границами бакетов buckets
+// (по возрастанию); бакет +Inf добавляется автоматически.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{buckets: buckets}
+	h.s = newSeries(name, help, "histogram", labels, func() *histogram {
+		return &histogram{counts: make([]uint64, len(buckets))}
+	})
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+
+	e := h.s.with(labelValues).value
+	for i, upper := range h.buckets {
+		if v <= upper {
+			e.counts[i]++
+		}
+	}
+	e.sum += v
+	e.count++
+}
+
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+
+	return h.s.with(labelValues).value.count
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	names := append(append([]string(nil), h.s.labels...), "le")
+
+	h.s.write(w, func(w *bufio.Writer, labelValues []string, v *histogram) {
+		values := append(append([]string(nil), labelValues...), "")
+		for i, upper := range h.buckets {
+			values[len(values)-1] = formatFloat(upper)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.s.name, formatLabels(names, values), v.counts[i])
+		}
+		values[len(values)-1] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.s.name, formatLabels(names, values), v.count)
+
+		labels := formatLabels(h.s.labels, labelValues)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.s.name, labels, formatFloat(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.s.name, labels, v.count)
+	})
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, escapeLabel(values[i]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
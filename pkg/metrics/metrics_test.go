@@ -0,0 +1,68 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/folivorra/task_queue/pkg/metrics"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := metrics.NewRegistry()
+
+	done := r.NewCounterVec("tasks_done_total", "Done tasks.", "type")
+	done.Inc("email")
+	done.Add(2, "report")
+	done.Inc(`we"ird`)
+
+	busy := r.NewGauge("busy_workers", "Busy workers.")
+	busy.Inc()
+	busy.Inc()
+	busy.Dec()
+
+	r.NewGaugeFunc("queue_depth", "Queue depth.", func() float64 { return 7 })
+
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.5, 0.1}, "type")
+	latency.Observe(0.05, "email")
+	latency.Observe(0.3, "email")
+	latency.Observe(2, "email")
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	want := `# HELP tasks_done_total Done tasks.
+# TYPE tasks_done_total counter
+tasks_done_total{type="email"} 1
+tasks_done_total{type="report"} 2
+tasks_done_total{type="we\"ird"} 1
+# HELP busy_workers Busy workers.
+# TYPE busy_workers gauge
+busy_workers 1
+# HELP queue_depth Queue depth.
+# TYPE queue_depth gauge
+queue_depth 7
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{type="email",le="0.1"} 1
+latency_seconds_bucket{type="email",le="0.5"} 2
+latency_seconds_bucket{type="email",le="+Inf"} 3
+latency_seconds_sum{type="email"} 2.35
+latency_seconds_count{type="email"} 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterVec_RejectsWrongLabelCount(t *testing.T) {
+	c := metrics.NewRegistry().NewCounterVec("c_total", "c", "type")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on label mismatch")
+		}
+	}()
+	c.Inc()
+}